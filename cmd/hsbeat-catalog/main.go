@@ -0,0 +1,225 @@
+/*
+hsbeat-catalog generates files from the counter catalog in the hsperfdata
+metricset.
+
+	-fields         rewrites the generated part of the metricset fields.yml
+	-docs           writes the counter reference in asciidoc
+	-template       adds dynamic templates to the index template
+	-template-es2x  adds dynamic templates to the index template for ES 2.x
+
+It is invoked by `make catalog`.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+const (
+	fieldsBegin = "    # Generated by hsbeat-catalog from the counter catalog. DO NOT EDIT.\n"
+	fieldsEnd   = "    # End of the counter catalog.\n"
+
+	fieldPrefix    = "hotspot.hsperfdata."
+	templatePrefix = "hsperfdata_"
+)
+
+var variabilityNames = map[int8]string{
+	hsperfdata.VariabilityConstant:  "constant",
+	hsperfdata.VariabilityMonotonic: "monotonic",
+	hsperfdata.VariabilityVariable:  "variable",
+}
+
+func main() {
+	fieldsPath := flag.String("fields", "", "fields.yml of the hsperfdata metricset")
+	docsPath := flag.String("docs", "", "asciidoc file for the counter reference")
+	templatePath := flag.String("template", "", "index template")
+	templateES2xPath := flag.String("template-es2x", "", "index template for Elasticsearch 2.x")
+	flag.Parse()
+
+	var err error
+	if *fieldsPath != "" && err == nil {
+		err = updateFields(*fieldsPath)
+	}
+	if *docsPath != "" && err == nil {
+		err = ioutil.WriteFile(*docsPath, []byte(generateDocs()), 0644)
+	}
+	if *templatePath != "" && err == nil {
+		err = updateTemplate(*templatePath, false)
+	}
+	if *templateES2xPath != "" && err == nil {
+		err = updateTemplate(*templateES2xPath, true)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// updateFields replaces the generated part in fields.yml with fields of
+// counters which have no wildcards. Counters with wildcards are mapped by
+// dynamic templates.
+func updateFields(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	text := string(content)
+	if begin := strings.Index(text, fieldsBegin); begin >= 0 {
+		end := strings.Index(text, fieldsEnd)
+		if end < begin {
+			return errors.New("End of the generated part is not found in " + path)
+		}
+		text = text[:begin] + text[end+len(fieldsEnd):]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(text)
+	buf.WriteString(fieldsBegin)
+	for _, c := range hsperfdata.Catalog {
+		if c.IsPattern() {
+			continue
+		}
+		buf.WriteString("    - name: " + c.Name + "\n")
+		if c.Type == hsperfdata.TypeString {
+			buf.WriteString("      type: keyword\n")
+		} else {
+			buf.WriteString("      type: long\n")
+			if c.Units == hsperfdata.UnitsBytes {
+				buf.WriteString("      format: bytes\n")
+			}
+		}
+		buf.WriteString("      description: >\n")
+		buf.WriteString("        " + c.Description + " (" + describeUnits(&c) + ")\n")
+	}
+	buf.WriteString(fieldsEnd)
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func describeUnits(c *hsperfdata.Counter) string {
+	return "units: " + c.Units + ", variability: " + variabilityNames[c.Variability]
+}
+
+func generateDocs() string {
+	var buf bytes.Buffer
+	buf.WriteString("////\nThis file is generated! See cmd/hsbeat-catalog\n////\n\n")
+	buf.WriteString("[[hotspot-counters]]\n== HotSpot Performance Counters\n\n")
+	buf.WriteString("This is the list of well-known performance counters in HotSpot. They are\n")
+	buf.WriteString("shipped as `hotspot.hsperfdata.<name>`. `*` in the name matches an index,\n")
+	buf.WriteString("`**` matches any name such as a system property.\n\n")
+	buf.WriteString("[options=\"header\"]\n|=======================================\n")
+	buf.WriteString("|Name |Type |Units |Variability |Description\n")
+	for _, c := range hsperfdata.Catalog {
+		buf.WriteString(fmt.Sprintf("|`%s` |%s |%s |%s |%s\n",
+			c.Name, c.Type, c.Units, variabilityNames[c.Variability], c.Description))
+	}
+	buf.WriteString("|=======================================\n")
+	return buf.String()
+}
+
+func mapping(fieldType string, es2x bool) map[string]interface{} {
+	switch fieldType {
+	case hsperfdata.TypeString:
+		if es2x {
+			return map[string]interface{}{"type": "string", "index": "not_analyzed", "ignore_above": 1024}
+		}
+		return map[string]interface{}{"type": "keyword", "ignore_above": 1024}
+	case "float":
+		return map[string]interface{}{"type": "float"}
+	default:
+		return map[string]interface{}{"type": "long"}
+	}
+}
+
+func dynamicTemplate(name, pathMatch, fieldType string, es2x bool) map[string]interface{} {
+	matchType := fieldType
+	if fieldType == "float" {
+		matchType = "double"
+	}
+	return map[string]interface{}{
+		templatePrefix + name: map[string]interface{}{
+			"path_match":         fieldPrefix + pathMatch,
+			"match_mapping_type": matchType,
+			"mapping":            mapping(fieldType, es2x),
+		},
+	}
+}
+
+// dynamicTemplates returns dynamic templates for `<counter>/diff`,
+// `<counter>/rate`, summaries of sampling (`<counter>/min`, `<counter>/max`
+// and `<counter>/avg`) and counters which have wildcards.
+func dynamicTemplates(es2x bool) []interface{} {
+	templates := []interface{}{
+		dynamicTemplate("diff", "*/diff", hsperfdata.TypeLong, es2x),
+		dynamicTemplate("rate", "*/rate", "float", es2x),
+		dynamicTemplate("min", "*/min", hsperfdata.TypeLong, es2x),
+		dynamicTemplate("max", "*/max", hsperfdata.TypeLong, es2x),
+		dynamicTemplate("avg", "*/avg", "float", es2x),
+	}
+	for _, c := range hsperfdata.Catalog {
+		if !c.IsPattern() {
+			continue
+		}
+		var elems []string
+		for _, elem := range strings.Split(c.Name, "/") {
+			if !strings.Contains(elem, "*") {
+				elems = append(elems, elem)
+			}
+		}
+		name := strings.Join(elems, "_")
+		pathMatch := strings.Replace(c.Name, "**", "*", -1)
+		templates = append(templates, dynamicTemplate(name, pathMatch, c.Type, es2x))
+	}
+	return templates
+}
+
+// updateTemplate adds dynamic templates to the index template which is
+// generated from etc/fields.yml. Dynamic templates which are added before
+// are replaced.
+func updateTemplate(path string, es2x bool) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var template map[string]interface{}
+	if err := json.Unmarshal(content, &template); err != nil {
+		return err
+	}
+
+	mappings, _ := template["mappings"].(map[string]interface{})
+	defaultMapping, ok := mappings["_default_"].(map[string]interface{})
+	if !ok {
+		return errors.New("_default_ mapping is not found in " + path)
+	}
+
+	templates := dynamicTemplates(es2x)
+	existing, _ := defaultMapping["dynamic_templates"].([]interface{})
+	for _, t := range existing {
+		generated := false
+		for name := range t.(map[string]interface{}) {
+			generated = strings.HasPrefix(name, templatePrefix)
+		}
+		if !generated {
+			templates = append(templates, t)
+		}
+	}
+	defaultMapping["dynamic_templates"] = templates
+
+	output, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, output, 0644)
+}
@@ -9,5 +9,14 @@ package include
 import (
 	// This list is automatically generated by `make imports`
 	_ "github.com/YaSuenag/hsbeat/module/hotspot"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/classloading"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/collector"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/compiler"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/gc"
 	_ "github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/jvminfo"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/liveness"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/memory"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/safepoint"
+	_ "github.com/YaSuenag/hsbeat/module/hotspot/threads"
 )
@@ -0,0 +1,168 @@
+package classloading
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+const (
+	loadedClasses   = "java/cls/loadedClasses"
+	unloadedClasses = "java/cls/unloadedClasses"
+)
+
+// classCounters maps counters of the number of classes to field names.
+var classCounters = map[string]string{
+	loadedClasses:                    "loaded",
+	unloadedClasses:                  "unloaded",
+	"java/cls/sharedLoadedClasses":   "shared_loaded",
+	"java/cls/sharedUnloadedClasses": "shared_unloaded",
+	"sun/cls/initializedClasses":     "initialized",
+	"sun/cls/linkedClasses":          "linked",
+	"sun/cls/appClassLoadCount":      "app_loaded",
+	"sun/cls/defineAppClasses":       "app_defined",
+	"sun/classloader/findClasses":    "find_class",
+}
+
+// bytesCounters maps counters of bytes to field names.
+var bytesCounters = map[string]string{
+	"sun/cls/loadedBytes":         "loaded",
+	"sun/cls/unloadedBytes":       "unloaded",
+	"sun/cls/sharedLoadedBytes":   "shared_loaded",
+	"sun/cls/sharedUnloadedBytes": "shared_unloaded",
+	"sun/cls/appClassBytes":       "app",
+	"sun/cls/sysClassBytes":       "sys",
+	"sun/cls/methodBytes":         "methods",
+}
+
+// timeCounters maps tick counters to field names.
+var timeCounters = map[string]string{
+	"sun/cls/time":                "total",
+	"sun/cls/parseClassTime":      "parse",
+	"sun/cls/classLinkedTime":     "link",
+	"sun/cls/classVerifyTime":     "verify",
+	"sun/cls/classInitTime":       "init",
+	"sun/cls/defineAppClassTime":  "define",
+	"sun/cls/appClassLoadTime":    "app_load",
+	"sun/cls/sysClassLoadTime":    "sys_load",
+	"sun/cls/sharedClassLoadTime": "shared_load",
+	"sun/cls/lookupSysClassTime":  "lookup_sys",
+}
+
+// nanosCounters maps counters in nanoseconds, which are written by the class
+// libraries, to field names.
+var nanosCounters = map[string]string{
+	"sun/urlClassLoader/readClassBytesTime": "url_read",
+	"sun/classloader/findClassTime":         "find_class",
+	"sun/classloader/parentDelegationTime":  "parent_delegation",
+}
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"java/cls/", "sun/cls/", "sun/classloader/", "sun/urlClassLoader/"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "classloading", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships an event of class loading of each Java process.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler      *hsperfdata.Sampler
+	counterReset string
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+		counterReset:  config.CounterReset,
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns an event
+// for each process. Errors are returned only if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		event := classLoadingEvent(pid, current, previous, m.Module().Config().Period, m.counterReset)
+		current.AddLabels(event)
+		events = append(events, event)
+	})
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch class loading metrics for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+// classLoadingEvent builds the event of the process. Diffs and rates are
+// added if the previous snapshot is available. Counters which go backwards
+// are shipped by the counter_reset policy.
+func classLoadingEvent(pid string, cur, prev *hsperfdata.Snapshot, period time.Duration, policy string) common.MapStr {
+	prevLongs, prevTime := hsperfdata.PreviousLongs(prev)
+	elapsed := hsperfdata.Elapsed(cur.Longs, prevLongs, cur.Time, prevTime, period)
+
+	event := common.MapStr{"pid": pid}
+	if elapsed.Seconds > 0 {
+		event["interval"] = elapsed.ToMapStr()
+	}
+	resets := hsperfdata.NewResets(policy)
+
+	loaded, loadedOk := cur.Longs[loadedClasses]
+	unloaded, unloadedOk := cur.Longs[unloadedClasses]
+	if loadedOk && unloadedOk {
+		event["count"] = loaded - unloaded
+	}
+
+	counters := func(names map[string]string) common.MapStr {
+		result := common.MapStr{}
+		for name, field := range names {
+			if counter, ok := hsperfdata.CounterMapStr(name, cur.Longs, prevLongs, elapsed, resets); ok {
+				result[field] = counter
+			}
+		}
+		return result
+	}
+	if classes := counters(classCounters); len(classes) > 0 {
+		event["classes"] = classes
+	}
+	if bytes := counters(bytesCounters); len(bytes) > 0 {
+		event["bytes"] = bytes
+	}
+
+	times := common.MapStr{}
+	for name, field := range timeCounters {
+		if t, ok := hsperfdata.TimeMapStr(name, cur.Longs, prevLongs, elapsed, resets); ok {
+			times[field] = t
+		}
+	}
+	for name, field := range nanosCounters {
+		if t, ok := hsperfdata.NanosTimeMapStr(name, cur.Longs, prevLongs, elapsed, resets); ok {
+			times[field] = t
+		}
+	}
+	if len(times) > 0 {
+		event["time"] = times
+	}
+	resets.AddTo(event)
+
+	return event
+}
@@ -0,0 +1,69 @@
+package classloading
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func newSnapshot(now time.Time, ticks, loaded, loadedBytes, parseTicks int64) *hsperfdata.Snapshot {
+	return hsperftest.NewSnapshot(now, ticks, hsperftest.Values{
+		"java/cls/loadedClasses":   loaded,
+		"java/cls/unloadedClasses": 100,
+		"sun/cls/loadedBytes":      loadedBytes,
+		"sun/cls/parseClassTime":   parseTicks,
+	})
+}
+
+func TestFirstClassLoadingEvent(t *testing.T) {
+	event := classLoadingEvent("1", newSnapshot(time.Now(), 1000, 1000, 4096, 300), nil, time.Second, hsperfdata.ResetValue)
+
+	hsperftest.AssertEquals(t, "1", event["pid"])
+	hsperftest.AssertEquals(t, int64(900), event["count"])
+	hsperftest.AssertEquals(t, int64(1000), hsperftest.GetValue(t, event, "classes.loaded.total"))
+	hsperftest.AssertEquals(t, int64(4096), hsperftest.GetValue(t, event, "bytes.loaded.total"))
+	hsperftest.AssertEquals(t, 300.0, hsperftest.GetValue(t, event, "time.parse.total.ms"))
+
+	if _, err := event.GetValue("classes.loaded.rate"); err == nil {
+		t.Errorf("rate should not be shipped at the first fetch")
+	}
+}
+
+func TestClassLoadingEventWithPrevious(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 1000, 4096, 300)
+	cur := newSnapshot(now.Add(2*time.Second), 3000, 1600, 8192, 700)
+
+	event := classLoadingEvent("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, int64(600), hsperftest.GetValue(t, event, "classes.loaded.diff"))
+	hsperftest.AssertEquals(t, 300.0, hsperftest.GetValue(t, event, "classes.loaded.rate"))
+	hsperftest.AssertEquals(t, 2048.0, hsperftest.GetValue(t, event, "bytes.loaded.rate"))
+	hsperftest.AssertEquals(t, 400.0, hsperftest.GetValue(t, event, "time.parse.diff.ms"))
+	hsperftest.AssertEquals(t, 0.2, hsperftest.GetValue(t, event, "time.parse.pct"))
+}
+
+func TestNanosCounters(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 1000, 4096, 300)
+	prev.Longs["sun/classloader/findClassTime"] = 5000000
+	cur := newSnapshot(now.Add(time.Second), 2000, 1000, 4096, 300)
+	cur.Longs["sun/classloader/findClassTime"] = 305000000
+
+	// nanoseconds are not converted by sun.os.hrt.frequency
+	event := classLoadingEvent("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, 305.0, hsperftest.GetValue(t, event, "time.find_class.total.ms"))
+	hsperftest.AssertEquals(t, 300.0, hsperftest.GetValue(t, event, "time.find_class.diff.ms"))
+	hsperftest.AssertEquals(t, 0.3, hsperftest.GetValue(t, event, "time.find_class.pct"))
+}
+
+func TestClassLoadingEventFromFile(t *testing.T) {
+	s := hsperftest.Read(t, hsperftest.ReadListing(t, "jdk17-g1.txt"))
+
+	event := classLoadingEvent("1", s, nil, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, int64(4509), event["count"])
+	hsperftest.AssertEquals(t, int64(4512), hsperftest.GetValue(t, event, "classes.loaded.total"))
+	hsperftest.AssertEquals(t, int64(8942208), hsperftest.GetValue(t, event, "bytes.loaded.total"))
+	hsperftest.AssertEquals(t, 0.111209, hsperftest.GetValue(t, event, "time.parse.total.ms"))
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "collector", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships metrics of the collection pipeline of hsbeat itself, e.g.
+// the number of attached JVMs and read errors. It updates the tracker which
+// is shared with other metricsets, so the number of attached JVMs is
+// available even if other metricsets are disabled, and JVMs are not
+// discovered twice.
+type MetricSet struct {
+	mb.BaseMetricSet
+	tracker *hsperfdata.Tracker
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		tracker:       hsperfdata.SharedTracker(&config, base.Module().Config().Period),
+	}, nil
+}
+
+// Fetch updates the shared tracker and returns an event with the metrics of
+// the collection pipeline. Discovery errors are logged, and the event is
+// shipped anyway because it is what tells that hsbeat cannot see JVMs.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	if _, errors := m.tracker.Update(); errors.HasErrors() {
+		logp.Warn("Could not discover or read java processes (%v)", errors)
+	}
+	return hsperfdata.CollectorStats(), nil
+}
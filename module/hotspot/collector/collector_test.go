@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+func TestFetchWithoutJVMs(t *testing.T) {
+	config := hsperfdata.DefaultConfig()
+	config.Pid = "999999999" // no hsperfdata file
+	m := &MetricSet{tracker: hsperfdata.NewTracker(&config)}
+
+	event, err := m.Fetch()
+	if err != nil {
+		t.Fatalf("the event should be shipped even if no JVM is found: %v", err)
+	}
+	for _, key := range []string{
+		"jvms.attached", "jvms.detached", "jvms.stale",
+		"discovery.count", "discovery.duration.ms",
+		"read.count", "read.bytes", "read.torn_retries", "read.permission_errors",
+		"parse.duration.max.ms",
+	} {
+		if _, err := event.GetValue(key); err != nil {
+			t.Errorf("%v should be shipped: %v", key, err)
+		}
+	}
+	if count, _ := event.GetValue("discovery.count"); count.(int64) < 1 {
+		t.Errorf("discovery should be counted: %v", count)
+	}
+	if _, ok := event["parse"].(common.MapStr); !ok {
+		t.Errorf("parse should be a group")
+	}
+}
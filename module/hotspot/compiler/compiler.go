@@ -0,0 +1,255 @@
+package compiler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// Kinds of events.
+const (
+	KindAggregate  = "aggregate"
+	KindThread     = "thread"
+	KindLastMethod = "last_method"
+)
+
+const (
+	totalTime     = "java/ci/totalTime"
+	compilerCount = "sun/ci/threads"
+)
+
+// compileCounters maps counters of compilations to field names.
+var compileCounters = map[string]string{
+	"sun/ci/totalCompiles":    "all",
+	"sun/ci/standardCompiles": "standard",
+	"sun/ci/osrCompiles":      "osr",
+	"sun/ci/totalBailouts":    "bailouts",
+	"sun/ci/totalInvalidates": "invalidations",
+}
+
+// bytesCounters maps counters of compiled bytecode to field names.
+var bytesCounters = map[string]string{
+	"sun/ci/standardBytes": "standard",
+	"sun/ci/osrBytes":      "osr",
+}
+
+// timeCounters maps tick counters to field names.
+var timeCounters = map[string]string{
+	totalTime:             "all",
+	"sun/ci/standardTime": "standard",
+	"sun/ci/osrTime":      "osr",
+}
+
+// lastMethods are string counters of the last methods. An event is shipped
+// when one of them is changed.
+var lastMethods = []struct {
+	name, typeName, status string
+}{
+	{"sun/ci/lastMethod", "sun/ci/lastType", "compiled"},
+	{"sun/ci/lastFailedMethod", "sun/ci/lastFailedType", "failed"},
+	{"sun/ci/lastInvalidatedMethod", "sun/ci/lastInvalidatedType", "invalidated"},
+}
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"java/ci/", "sun/ci/"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "compiler", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships events of the JIT compiler of each Java process.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler      *hsperfdata.Sampler
+	counterReset string
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+		counterReset:  config.CounterReset,
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns an
+// aggregate event, an event for each compiler thread and events of changed
+// last methods for each process. Errors are returned only if no events were
+// collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		pidEvents := compilerEvents(pid, current, previous, m.Module().Config().Period, m.counterReset)
+		current.AddLabels(pidEvents...)
+		events = append(events, pidEvents...)
+	})
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch compiler metrics for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+func threadCounter(thread int, name string) string {
+	return "sun/ci/compilerThread/" + strconv.Itoa(thread) + "/" + name
+}
+
+func compilerEvents(pid string, cur, prev *hsperfdata.Snapshot, period time.Duration, policy string) []common.MapStr {
+	prevLongs, prevTime := hsperfdata.PreviousLongs(prev)
+	elapsed := hsperfdata.Elapsed(cur.Longs, prevLongs, cur.Time, prevTime, period)
+
+	events := []common.MapStr{aggregateEvent(pid, cur, prevLongs, elapsed, policy)}
+	for thread := 0; ; thread++ {
+		event, ok := threadEvent(pid, thread, cur, prevLongs, elapsed, policy)
+		if !ok {
+			break
+		}
+		events = append(events, event)
+	}
+	if prev != nil {
+		events = append(events, lastMethodEvents(pid, cur, prev)...)
+	}
+	return events
+}
+
+func newEvent(pid, kind string, elapsed hsperfdata.Interval) common.MapStr {
+	event := common.MapStr{
+		"pid":  pid,
+		"kind": kind,
+	}
+	if elapsed.Seconds > 0 {
+		event["interval"] = elapsed.ToMapStr()
+	}
+	return event
+}
+
+// aggregateEvent builds the event of all compilations in the process.
+// Counters which go backwards are shipped by the counter_reset policy.
+func aggregateEvent(pid string, cur *hsperfdata.Snapshot, prev map[string]int64, elapsed hsperfdata.Interval, policy string) common.MapStr {
+	event := newEvent(pid, KindAggregate, elapsed)
+	resets := hsperfdata.NewResets(policy)
+
+	if v, ok := cur.Longs[compilerCount]; ok {
+		event["threads"] = v
+	}
+
+	counters := func(names map[string]string) common.MapStr {
+		result := common.MapStr{}
+		for name, field := range names {
+			if counter, ok := hsperfdata.CounterMapStr(name, cur.Longs, prev, elapsed, resets); ok {
+				result[field] = counter
+			}
+		}
+		return result
+	}
+	if compiles := counters(compileCounters); len(compiles) > 0 {
+		event["compiles"] = compiles
+	}
+	if bytes := counters(bytesCounters); len(bytes) > 0 {
+		event["bytes"] = bytes
+	}
+
+	times := common.MapStr{}
+	for name, field := range timeCounters {
+		if t, ok := hsperfdata.TimeMapStr(name, cur.Longs, prev, elapsed, resets); ok {
+			times[field] = t
+		}
+	}
+	if len(times) > 0 {
+		event["time"] = times
+	}
+
+	codeCache := common.MapStr{}
+	if v, ok := cur.Longs["sun/ci/nmethodCodeSize"]; ok {
+		codeCache["code"] = common.MapStr{"bytes": v}
+	}
+	if v, ok := cur.Longs["sun/ci/nmethodSize"]; ok {
+		codeCache["total"] = common.MapStr{"bytes": v}
+	}
+	if len(codeCache) > 0 {
+		event["code_cache"] = codeCache
+	}
+
+	if method, ok := cur.Strings["sun/ci/lastMethod"]; ok {
+		last := common.MapStr{"method": method}
+		if v, ok := cur.Longs["sun/ci/lastSize"]; ok {
+			last["size"] = common.MapStr{"bytes": v}
+		}
+		event["last"] = last
+	}
+	resets.AddTo(event)
+
+	return event
+}
+
+// threadEvent builds the event of the compiler thread. It returns false if
+// the thread does not exist.
+func threadEvent(pid string, thread int, cur *hsperfdata.Snapshot, prev map[string]int64, elapsed hsperfdata.Interval, policy string) (common.MapStr, bool) {
+	resets := hsperfdata.NewResets(policy)
+	compiles, ok := hsperfdata.CounterMapStr(threadCounter(thread, "compiles"), cur.Longs, prev, elapsed, resets)
+	if !ok {
+		return nil, false
+	}
+
+	event := newEvent(pid, KindThread, elapsed)
+	event["thread"] = thread
+	event["compiles"] = compiles
+	if t, ok := hsperfdata.TimeMapStr(threadCounter(thread, "time"), cur.Longs, prev, elapsed, resets); ok {
+		event["time"] = t
+	}
+	if method, ok := cur.Strings[threadCounter(thread, "method")]; ok && method != "" {
+		event["method"] = method
+	}
+	if v, ok := cur.Longs[threadCounter(thread, "type")]; ok {
+		event["compile_type"] = v
+	}
+	resets.AddTo(event)
+	return event, true
+}
+
+// lastMethodEvents builds events for last methods which are changed since
+// the previous fetch. Frequent events of invalidated methods indicate a
+// deoptimization storm.
+func lastMethodEvents(pid string, cur, prev *hsperfdata.Snapshot) []common.MapStr {
+	var events []common.MapStr
+	for _, last := range lastMethods {
+		method, ok := cur.Strings[last.name]
+		if !ok || method == "" || method == prev.Strings[last.name] {
+			continue
+		}
+
+		event := common.MapStr{
+			"pid":    pid,
+			"kind":   KindLastMethod,
+			"status": last.status,
+			"method": method,
+		}
+		if previous, ok := prev.Strings[last.name]; ok && previous != "" {
+			event["previous_method"] = previous
+		}
+		if v, ok := cur.Longs[last.typeName]; ok {
+			event["compile_type"] = v
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,99 @@
+package compiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func newSnapshot(now time.Time, ticks, compiles int64, lastMethod string) *hsperfdata.Snapshot {
+	return hsperftest.NewSnapshot(now, ticks, hsperftest.Values{
+		"sun/ci/threads":                   2,
+		"sun/ci/totalCompiles":             compiles,
+		"sun/ci/totalInvalidates":          3,
+		"java/ci/totalTime":                compiles * 10,
+		"sun/ci/nmethodCodeSize":           4096,
+		"sun/ci/lastType":                  1,
+		"sun/ci/compilerThread/0/compiles": compiles / 2,
+		"sun/ci/compilerThread/0/time":     compiles * 5,
+		"sun/ci/compilerThread/1/compiles": compiles / 2,
+		"sun/ci/compilerThread/1/time":     compiles * 5,
+		"sun/ci/lastMethod":                lastMethod,
+		"sun/ci/lastFailedMethod":          "",
+		"sun/ci/compilerThread/0/method":   "",
+		"sun/ci/compilerThread/1/method":   "java/lang/String hashCode",
+		"sun/ci/lastInvalidatedMethod":     "",
+	})
+}
+
+func TestFirstCompilerEvents(t *testing.T) {
+	events := compilerEvents("1", newSnapshot(time.Now(), 1000, 100, "java/lang/Object <init>"), nil, time.Second, hsperfdata.ResetValue)
+	if len(events) != 3 {
+		t.Fatalf("3 events are expected, but %v", len(events))
+	}
+
+	aggregate := events[0]
+	hsperftest.AssertEquals(t, KindAggregate, aggregate["kind"])
+	hsperftest.AssertEquals(t, int64(2), aggregate["threads"])
+	hsperftest.AssertEquals(t, int64(100), hsperftest.GetValue(t, aggregate, "compiles.all.total"))
+	hsperftest.AssertEquals(t, 1000.0, hsperftest.GetValue(t, aggregate, "time.all.total.ms"))
+	hsperftest.AssertEquals(t, int64(4096), hsperftest.GetValue(t, aggregate, "code_cache.code.bytes"))
+	hsperftest.AssertEquals(t, "java/lang/Object <init>", hsperftest.GetValue(t, aggregate, "last.method"))
+
+	thread := events[2]
+	hsperftest.AssertEquals(t, KindThread, thread["kind"])
+	hsperftest.AssertEquals(t, 1, thread["thread"])
+	hsperftest.AssertEquals(t, int64(50), hsperftest.GetValue(t, thread, "compiles.total"))
+	hsperftest.AssertEquals(t, "java/lang/String hashCode", thread["method"])
+	if _, exists := events[1]["method"]; exists {
+		t.Errorf("method of the idle compiler thread should not be shipped")
+	}
+}
+
+func TestCompilerEventsWithPrevious(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 100, "java/lang/Object <init>")
+	cur := newSnapshot(now.Add(2*time.Second), 3000, 140, "java/lang/String equals")
+
+	events := compilerEvents("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	if len(events) != 4 {
+		t.Fatalf("4 events are expected, but %v", len(events))
+	}
+
+	hsperftest.AssertEquals(t, 20.0, hsperftest.GetValue(t, events[0], "compiles.all.rate"))
+	hsperftest.AssertEquals(t, 0.2, hsperftest.GetValue(t, events[0], "time.all.pct"))
+	hsperftest.AssertEquals(t, 10.0, hsperftest.GetValue(t, events[1], "compiles.rate"))
+
+	last := events[3]
+	hsperftest.AssertEquals(t, KindLastMethod, last["kind"])
+	hsperftest.AssertEquals(t, "compiled", last["status"])
+	hsperftest.AssertEquals(t, "java/lang/String equals", last["method"])
+	hsperftest.AssertEquals(t, "java/lang/Object <init>", last["previous_method"])
+	hsperftest.AssertEquals(t, int64(1), last["compile_type"])
+}
+
+func TestUnchangedLastMethod(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 100, "java/lang/Object <init>")
+	cur := newSnapshot(now.Add(time.Second), 2000, 100, "java/lang/Object <init>")
+
+	if events := lastMethodEvents("1", cur, prev); len(events) != 0 {
+		t.Errorf("no events are expected, but %v", events)
+	}
+}
+
+func TestCompilerEventsFromFile(t *testing.T) {
+	s := hsperftest.Read(t, hsperftest.ReadListing(t, "jdk17-g1.txt"))
+
+	events := compilerEvents("1", s, nil, time.Second, hsperfdata.ResetValue)
+	if len(events) == 0 {
+		t.Fatalf("the aggregate event is expected")
+	}
+	aggregate := events[0]
+	hsperftest.AssertEquals(t, KindAggregate, aggregate["kind"])
+	hsperftest.AssertEquals(t, int64(3), aggregate["threads"])
+	hsperftest.AssertEquals(t, int64(3032), hsperftest.GetValue(t, aggregate, "compiles.all.total"))
+	hsperftest.AssertEquals(t, "java/util/HashMap getNode", hsperftest.GetValue(t, aggregate, "last.method"))
+}
@@ -0,0 +1,161 @@
+package gc
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// Kinds of events.
+const (
+	KindCollector = "collector"
+
+	// KindCollections is the summary of collections by a collector in a
+	// period. HotSpot exposes only cumulative counters and the entry and exit
+	// time of the last collection, so each collection cannot be shipped.
+	KindCollections = "collections"
+)
+
+// Generations whose occupancy is shipped in collections events.
+const (
+	youngGen = 0
+	oldGen   = 1
+)
+
+// collection is the collections by a collector between two snapshots.
+type collection struct {
+	index int
+	name  string
+	count int64
+	entry int64 // ticks when the last collection started
+	exit  int64 // ticks when the last collection finished
+}
+
+// collectionEvents detects collections between the previous and the current
+// snapshot from invocations of each collector, and builds one event for each
+// collector which collected, not for each collection. The count is of all
+// collections in the period, the duration is of the last collection, and
+// occupancy before and after the collection is added only if exactly one
+// collection happened in the period.
+func collectionEvents(pid string, cur, prev *hsperfdata.Snapshot) []common.MapStr {
+	if prev == nil {
+		return nil
+	}
+
+	collectors := hsperfdata.Collectors(cur.Strings)
+	algorithm := hsperfdata.DetectGC(cur.Strings[gcPolicy], hsperfdata.CollectorNames(cur.Strings))
+
+	var collections []collection
+	var total int64
+	latest := -1 // the collection which started last
+	for _, collector := range collectors {
+		i := collector.Index
+		invocations := hsperfdata.CollectorCounter(i, "invocations")
+		count := cur.Longs[invocations] - prev.Longs[invocations]
+		if count <= 0 {
+			continue // no collection, or the counter is reset
+		}
+
+		c := collection{
+			index: i,
+			name:  collector.Name,
+			count: count,
+			entry: cur.Longs[hsperfdata.CollectorCounter(i, "lastEntryTime")],
+			exit:  cur.Longs[hsperfdata.CollectorCounter(i, "lastExitTime")],
+		}
+		if latest < 0 || c.entry > collections[latest].entry {
+			latest = len(collections)
+		}
+		collections = append(collections, c)
+		total += count
+	}
+
+	events := make([]common.MapStr, 0, len(collections))
+	for n, c := range collections {
+		collector := common.MapStr{
+			"index": c.index,
+			"name":  c.name,
+		}
+		if role := hsperfdata.CollectionRole(algorithm, c.index, c.name); role != "" {
+			collector["role"] = role
+		}
+
+		inProgress := c.exit < c.entry
+		event := common.MapStr{
+			"pid":         pid,
+			"kind":        KindCollections,
+			"algorithm":   algorithm,
+			"collector":   collector,
+			"count":       c.count,
+			"multiple":    total > 1,
+			"in_progress": inProgress,
+		}
+		if n == latest {
+			if inProgress {
+				event["cause"] = cur.Strings[gcCause]
+			} else if cause, ok := cur.Strings[gcLastCause]; ok {
+				event["cause"] = cause
+			}
+		}
+
+		if ms, ok := hsperfdata.TicksToMillis(c.entry, cur.Longs); ok {
+			event["start"] = common.MapStr{"ms": ms}
+		}
+		if !inProgress {
+			if ms, ok := hsperfdata.TicksToMillis(c.exit, cur.Longs); ok {
+				event["end"] = common.MapStr{"ms": ms}
+			}
+			if ms, ok := hsperfdata.TicksToMillis(c.exit-c.entry, cur.Longs); ok {
+				event["duration"] = common.MapStr{"ms": ms}
+			}
+		}
+		gcTime := hsperfdata.CollectorCounter(c.index, "time")
+		if ms, ok := hsperfdata.TicksToMillis(cur.Longs[gcTime]-prev.Longs[gcTime], cur.Longs); ok {
+			event["time"] = common.MapStr{"ms": ms}
+		}
+
+		if total == 1 && !inProgress {
+			if occupancy := occupancy(algorithm, cur.Longs, prev.Longs); len(occupancy) > 0 {
+				event["occupancy"] = occupancy
+			}
+		}
+
+		events = append(events, event)
+	}
+	return events
+}
+
+// occupancy builds used bytes of generations and the whole heap in the
+// previous snapshot (before) and the current snapshot (after).
+func occupancy(algorithm string, cur, prev map[string]int64) common.MapStr {
+	result := common.MapStr{}
+	var heapBefore, heapAfter int64
+	found := false
+	for name, gen := range map[string]int{"young": youngGen, "old": oldGen} {
+		before, ok := hsperfdata.GenerationUsed(prev, gen)
+		if !ok {
+			continue
+		}
+		after, ok := hsperfdata.GenerationUsed(cur, gen)
+		if !ok {
+			continue
+		}
+		if hsperfdata.IsGenerational(algorithm) {
+			result[name] = beforeAfter(before, after)
+		}
+		heapBefore += before
+		heapAfter += after
+		found = true
+	}
+	if found {
+		result["heap"] = beforeAfter(heapBefore, heapAfter)
+	}
+	return result
+}
+
+func beforeAfter(before, after int64) common.MapStr {
+	return common.MapStr{
+		"before": common.MapStr{"bytes": before},
+		"after":  common.MapStr{"bytes": after},
+	}
+}
@@ -0,0 +1,127 @@
+package gc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func withOccupancy(s *hsperfdata.Snapshot, young, old int64) *hsperfdata.Snapshot {
+	s.Longs["sun/gc/generation/0/space/0/used"] = young
+	s.Longs["sun/gc/generation/1/space/0/used"] = old
+	return s
+}
+
+func TestNoCollectionEventsAtFirstFetch(t *testing.T) {
+	events := collectionEvents("1", newSnapshot(time.Now(), 1000, 5, 200), nil)
+	hsperftest.AssertEquals(t, 0, len(events))
+}
+
+func TestSingleCollectionEvent(t *testing.T) {
+	now := time.Now()
+	prev := withOccupancy(newSnapshot(now, 800, 5, 200), 1000, 500)
+	cur := withOccupancy(newSnapshot(now.Add(time.Second), 1000, 6, 250), 100, 600)
+
+	events := collectionEvents("1", cur, prev)
+	if len(events) != 1 {
+		t.Fatalf("1 event is expected, but %v", len(events))
+	}
+
+	event := events[0]
+	hsperftest.AssertEquals(t, KindCollections, event["kind"])
+	hsperftest.AssertEquals(t, hsperfdata.CollectionMinor, hsperftest.GetValue(t, event, "collector.role"))
+	hsperftest.AssertEquals(t, int64(1), event["count"])
+	hsperftest.AssertEquals(t, false, event["multiple"])
+	hsperftest.AssertEquals(t, "G1 Evacuation Pause", event["cause"])
+	hsperftest.AssertEquals(t, 900.0, hsperftest.GetValue(t, event, "start.ms"))
+	hsperftest.AssertEquals(t, 950.0, hsperftest.GetValue(t, event, "end.ms"))
+	hsperftest.AssertEquals(t, 50.0, hsperftest.GetValue(t, event, "duration.ms"))
+	hsperftest.AssertEquals(t, 50.0, hsperftest.GetValue(t, event, "time.ms"))
+	hsperftest.AssertEquals(t, int64(1000), hsperftest.GetValue(t, event, "occupancy.young.before.bytes"))
+	hsperftest.AssertEquals(t, int64(100), hsperftest.GetValue(t, event, "occupancy.young.after.bytes"))
+	hsperftest.AssertEquals(t, int64(600), hsperftest.GetValue(t, event, "occupancy.old.after.bytes"))
+	hsperftest.AssertEquals(t, int64(1500), hsperftest.GetValue(t, event, "occupancy.heap.before.bytes"))
+	hsperftest.AssertEquals(t, int64(700), hsperftest.GetValue(t, event, "occupancy.heap.after.bytes"))
+}
+
+func TestMultipleCollectionEvents(t *testing.T) {
+	now := time.Now()
+	prev := withOccupancy(newSnapshot(now, 800, 5, 200), 1000, 500)
+	cur := withOccupancy(newSnapshot(now.Add(time.Second), 1000, 8, 320), 100, 300)
+	cur.Longs["sun/gc/collector/1/invocations"] = 1
+	cur.Longs["sun/gc/collector/1/time"] = 80
+	cur.Longs["sun/gc/collector/1/lastEntryTime"] = 960
+	cur.Longs["sun/gc/collector/1/lastExitTime"] = 990
+	cur.Strings["sun/gc/lastCause"] = "Allocation Failure"
+
+	events := collectionEvents("1", cur, prev)
+	if len(events) != 2 {
+		t.Fatalf("2 events are expected, but %v", len(events))
+	}
+
+	young, full := events[0], events[1]
+	hsperftest.AssertEquals(t, int64(3), young["count"])
+	hsperftest.AssertEquals(t, true, young["multiple"])
+	hsperftest.AssertEquals(t, 120.0, hsperftest.GetValue(t, young, "time.ms"))
+	hsperftest.AssertEquals(t, true, full["multiple"])
+	hsperftest.AssertEquals(t, "Allocation Failure", full["cause"])
+	hsperftest.AssertEquals(t, 30.0, hsperftest.GetValue(t, full, "duration.ms"))
+	for _, event := range events {
+		if _, exists := event["occupancy"]; exists {
+			t.Errorf("occupancy should not be shipped if several collections happened")
+		}
+	}
+	if _, exists := young["cause"]; exists {
+		t.Errorf("cause should be shipped only for the latest collection")
+	}
+}
+
+func TestCollectionEventInProgress(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 800, 5, 200)
+	cur := newSnapshot(now.Add(time.Second), 1000, 6, 200)
+	cur.Longs["sun/gc/collector/0/lastEntryTime"] = 990
+	cur.Strings["sun/gc/cause"] = "G1 Humongous Allocation"
+
+	event := collectionEvents("1", cur, prev)[0]
+	hsperftest.AssertEquals(t, true, event["in_progress"])
+	hsperftest.AssertEquals(t, "G1 Humongous Allocation", event["cause"])
+	if _, exists := event["duration"]; exists {
+		t.Errorf("duration should not be shipped while the collection is in progress")
+	}
+}
+
+func TestNoCollectionEventsAfterReset(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 800, 50, 2000)
+	cur := newSnapshot(now.Add(time.Second), 1000, 6, 200)
+
+	hsperftest.AssertEquals(t, 0, len(collectionEvents("1", cur, prev)))
+}
+
+func TestG1CollectionEventFromFile(t *testing.T) {
+	counters := hsperftest.ReadListing(t, "jdk17-g1.txt")
+	prev := hsperftest.Read(t, counters)
+	counters = hsperftest.Set(counters, "sun.os.hrt.ticks", int64(11234567890))
+	counters = hsperftest.Set(counters, "sun.gc.collector.0.invocations", int64(43))
+	counters = hsperftest.Set(counters, "sun.gc.collector.0.time", int64(236204567))
+	counters = hsperftest.Set(counters, "sun.gc.collector.0.lastEntryTime", int64(10500000000))
+	counters = hsperftest.Set(counters, "sun.gc.collector.0.lastExitTime", int64(10505000000))
+	counters = hsperftest.Set(counters, "sun.gc.generation.0.space.0.used", int64(0))
+	cur := hsperftest.Read(t, counters)
+
+	events := collectionEvents("1", cur, prev)
+	if len(events) != 1 {
+		t.Fatalf("1 event is expected, but %v", len(events))
+	}
+	event := events[0]
+	hsperftest.AssertEquals(t, hsperfdata.GCG1, event["algorithm"])
+	hsperftest.AssertEquals(t, hsperfdata.CollectionMinor, hsperftest.GetValue(t, event, "collector.role"))
+	hsperftest.AssertEquals(t, int64(1), event["count"])
+	hsperftest.AssertEquals(t, "G1 Evacuation Pause", event["cause"])
+	hsperftest.AssertEquals(t, 10500.0, hsperftest.GetValue(t, event, "start.ms"))
+	hsperftest.AssertEquals(t, 5.0, hsperftest.GetValue(t, event, "duration.ms"))
+	hsperftest.AssertEquals(t, int64(8388608), hsperftest.GetValue(t, event, "occupancy.young.after.bytes")) // survivors
+}
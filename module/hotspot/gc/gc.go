@@ -0,0 +1,150 @@
+package gc
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+const (
+	gcCause     = "sun/gc/cause"
+	gcLastCause = "sun/gc/lastCause"
+	gcPolicy    = "sun/gc/policy/name"
+)
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"sun/gc/"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "gc", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships one event per garbage collector of each Java process, and
+// one event per collector which collected since the previous fetch.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler      *hsperfdata.Sampler
+	counterReset string
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+		counterReset:  config.CounterReset,
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns an event
+// for each collector and for collections since the previous fetch. Errors are
+// returned only if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		pidEvents := collectorEvents(pid, current, previous, m.Module().Config().Period, m.counterReset)
+		pidEvents = append(pidEvents, collectionEvents(pid, current, previous)...)
+		current.AddLabels(pidEvents...)
+		events = append(events, pidEvents...)
+	})
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch GC metrics for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+// collectorEvents builds events for all collectors in the snapshot. Diffs and
+// rates are added if the previous snapshot is available. Counters which go
+// backwards are shipped by the counter_reset policy.
+func collectorEvents(pid string, cur, prev *hsperfdata.Snapshot, period time.Duration, policy string) []common.MapStr {
+	collectors := hsperfdata.Collectors(cur.Strings)
+	algorithm := hsperfdata.DetectGC(cur.Strings[gcPolicy], hsperfdata.CollectorNames(cur.Strings))
+
+	prevLongs, prevTime := hsperfdata.PreviousLongs(prev)
+	elapsed := hsperfdata.Elapsed(cur.Longs, prevLongs, cur.Time, prevTime, period)
+
+	events := make([]common.MapStr, 0, len(collectors))
+	for _, c := range collectors {
+		i := c.Index
+		collector := common.MapStr{
+			"index": i,
+			"name":  c.Name,
+		}
+		if role := hsperfdata.CollectionRole(algorithm, i, c.Name); role != "" {
+			collector["role"] = role
+		}
+
+		event := common.MapStr{
+			"pid":       pid,
+			"kind":      KindCollector,
+			"algorithm": algorithm,
+			"collector": collector,
+		}
+		if cause, ok := cur.Strings[gcCause]; ok {
+			event["cause"] = cause
+		}
+		if cause, ok := cur.Strings[gcLastCause]; ok {
+			event["last_cause"] = cause
+		}
+		if elapsed.Seconds > 0 {
+			event["interval"] = elapsed.ToMapStr()
+		}
+
+		resets := hsperfdata.NewResets(policy)
+		if invocations, ok := hsperfdata.CounterMapStr(hsperfdata.CollectorCounter(i, "invocations"), cur.Longs, prevLongs, elapsed, resets); ok {
+			event["invocations"] = invocations
+		}
+		if t, ok := hsperfdata.TimeMapStr(hsperfdata.CollectorCounter(i, "time"), cur.Longs, prevLongs, elapsed, resets); ok {
+			event["time"] = t
+		}
+		addLastCollection(event, i, cur.Longs)
+		resets.AddTo(event)
+
+		events = append(events, event)
+	}
+	return events
+}
+
+// addLastCollection adds the entry and exit time of the last collection in
+// milliseconds since the JVM start. The duration is available only if the
+// collection has finished.
+func addLastCollection(event common.MapStr, index int, longs map[string]int64) {
+	entry, entryOk := longs[hsperfdata.CollectorCounter(index, "lastEntryTime")]
+	exit, exitOk := longs[hsperfdata.CollectorCounter(index, "lastExitTime")]
+	if !entryOk || !exitOk || entry == 0 {
+		return // no collection yet
+	}
+
+	last := common.MapStr{}
+	if ms, ok := hsperfdata.TicksToMillis(entry, longs); ok {
+		last["entry"] = common.MapStr{"ms": ms}
+	}
+	if ms, ok := hsperfdata.TicksToMillis(exit, longs); ok {
+		last["exit"] = common.MapStr{"ms": ms}
+	}
+	last["in_progress"] = exit < entry
+	if exit >= entry {
+		if ms, ok := hsperfdata.TicksToMillis(exit-entry, longs); ok {
+			last["duration"] = common.MapStr{"ms": ms}
+		}
+	}
+	event["last"] = last
+}
@@ -0,0 +1,93 @@
+package gc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func newSnapshot(now time.Time, ticks, invocations, gcTicks int64) *hsperfdata.Snapshot {
+	return hsperftest.NewSnapshot(now, ticks, hsperftest.Values{
+		"sun/gc/collector/0/invocations":   invocations,
+		"sun/gc/collector/0/time":          gcTicks,
+		"sun/gc/collector/0/lastEntryTime": 900,
+		"sun/gc/collector/0/lastExitTime":  950,
+		"sun/gc/collector/1/invocations":   0,
+		"sun/gc/collector/1/time":          0,
+		"sun/gc/collector/1/lastEntryTime": 0,
+		"sun/gc/collector/1/lastExitTime":  0,
+		"sun/gc/policy/name":               "GarbageFirst",
+		"sun/gc/collector/0/name":          "G1 incremental collections",
+		"sun/gc/collector/1/name":          "G1 stop-the-world full collections",
+		"sun/gc/cause":                     "No GC",
+		"sun/gc/lastCause":                 "G1 Evacuation Pause",
+	})
+}
+
+func TestFirstCollectorEvents(t *testing.T) {
+	events := collectorEvents("1", newSnapshot(time.Now(), 1000, 5, 200), nil, time.Second, hsperfdata.ResetValue)
+	if len(events) != 2 {
+		t.Fatalf("2 events are expected, but %v", len(events))
+	}
+
+	young := events[0]
+	hsperftest.AssertEquals(t, "1", young["pid"])
+	hsperftest.AssertEquals(t, hsperfdata.GCG1, young["algorithm"])
+	hsperftest.AssertEquals(t, "G1 incremental collections", hsperftest.GetValue(t, young, "collector.name"))
+	hsperftest.AssertEquals(t, hsperfdata.CollectionMinor, hsperftest.GetValue(t, young, "collector.role"))
+	hsperftest.AssertEquals(t, "No GC", young["cause"])
+	hsperftest.AssertEquals(t, "G1 Evacuation Pause", young["last_cause"])
+	hsperftest.AssertEquals(t, int64(5), hsperftest.GetValue(t, young, "invocations.total"))
+	hsperftest.AssertEquals(t, 200.0, hsperftest.GetValue(t, young, "time.total.ms"))
+	hsperftest.AssertEquals(t, 50.0, hsperftest.GetValue(t, young, "last.duration.ms"))
+	hsperftest.AssertEquals(t, false, hsperftest.GetValue(t, young, "last.in_progress"))
+
+	for _, key := range []string{"invocations.diff", "invocations.rate", "time.diff", "interval"} {
+		if _, err := young.GetValue(key); err == nil {
+			t.Errorf("%v should not be shipped at the first fetch", key)
+		}
+	}
+
+	if _, exists := events[1]["last"]; exists {
+		t.Errorf("last should not be shipped before the first collection")
+	}
+}
+
+func TestCollectorEventsWithPrevious(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 5, 200)
+	cur := newSnapshot(now.Add(2*time.Second), 3000, 9, 300)
+
+	young := collectorEvents("1", cur, prev, time.Second, hsperfdata.ResetValue)[0]
+	hsperftest.AssertEquals(t, int64(4), hsperftest.GetValue(t, young, "invocations.diff"))
+	hsperftest.AssertEquals(t, 2.0, hsperftest.GetValue(t, young, "invocations.rate"))
+	hsperftest.AssertEquals(t, 100.0, hsperftest.GetValue(t, young, "time.diff.ms"))
+	hsperftest.AssertEquals(t, 0.05, hsperftest.GetValue(t, young, "time.pct"))
+	hsperftest.AssertEquals(t, 2000.0, hsperftest.GetValue(t, young, "interval.ms"))
+}
+
+func TestCollectionInProgress(t *testing.T) {
+	s := newSnapshot(time.Now(), 1000, 5, 200)
+	s.Longs["sun/gc/collector/0/lastEntryTime"] = 990
+
+	young := collectorEvents("1", s, nil, time.Second, hsperfdata.ResetValue)[0]
+	hsperftest.AssertEquals(t, true, hsperftest.GetValue(t, young, "last.in_progress"))
+	if _, err := young.GetValue("last.duration"); err == nil {
+		t.Errorf("duration should not be shipped while the collection is in progress")
+	}
+}
+
+func TestZGCCollectorEvents(t *testing.T) {
+	s := hsperftest.Read(t, hsperftest.ReadListing(t, "jdk17-zgc.txt"))
+
+	events := collectorEvents("1", s, nil, time.Second, hsperfdata.ResetValue)
+	if len(events) != 1 {
+		t.Fatalf("1 event is expected, but %v", len(events))
+	}
+	hsperftest.AssertEquals(t, hsperfdata.GCZ, events[0]["algorithm"])
+	hsperftest.AssertEquals(t, 2, hsperftest.GetValue(t, events[0], "collector.index"))
+	hsperftest.AssertEquals(t, hsperfdata.CollectionMajor, hsperftest.GetValue(t, events[0], "collector.role"))
+	hsperftest.AssertEquals(t, int64(36), hsperftest.GetValue(t, events[0], "invocations.total"))
+}
@@ -0,0 +1,273 @@
+package hsperfdata
+
+import (
+	"strings"
+)
+
+// Units of counters. They are the same as PerfData::Units in HotSpot except
+// UnitsNanoseconds.
+const (
+	UnitsNone   = "none"
+	UnitsBytes  = "bytes"
+	UnitsTicks  = "ticks"
+	UnitsEvents = "events"
+	UnitsString = "string"
+	UnitsHertz  = "hertz"
+
+	// UnitsNanoseconds is of counters which are created by
+	// jdk.internal.perf.PerfCounter on the Java side. They are written with
+	// U_None and V_Variable, and hold System.nanoTime() differences instead
+	// of ticks of sun.os.hrt.
+	UnitsNanoseconds = "nanoseconds"
+)
+
+// Types of counter values.
+const (
+	TypeLong   = "long"
+	TypeString = "string"
+)
+
+// Counter describes a well-known HotSpot performance counter.
+type Counter struct {
+	// Name is separated by '/' as the event field. "*" matches one element
+	// such as a collector index, "**" at the end matches one or more
+	// elements such as a system property name.
+	Name        string
+	Type        string
+	Units       string
+	Variability int8
+	Description string
+}
+
+// IsPattern returns true if the name of the counter contains wildcards.
+func (c *Counter) IsPattern() bool {
+	return strings.Contains(c.Name, "*")
+}
+
+// Match returns true if the counter name matches with the catalog entry.
+func (c *Counter) Match(name string) bool {
+	return matchCounterName(strings.Split(c.Name, "/"), strings.Split(name, "/"))
+}
+
+func matchCounterName(pattern []string, name []string) bool {
+	for i, elem := range pattern {
+		if elem == "**" {
+			return len(name) > i
+		}
+		if i >= len(name) {
+			return false
+		}
+		if elem != "*" && elem != name[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(name)
+}
+
+// LookupCounter finds the catalog entry of the counter. The name can be
+// separated either by '.' (as HotSpot) or '/' (as the event field).
+func LookupCounter(name string) (*Counter, bool) {
+	name = strings.Replace(name, ".", "/", -1)
+	if c, exists := catalogByName[name]; exists {
+		return c, true
+	}
+	for _, c := range catalogPatterns {
+		if c.Match(name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+var (
+	catalogByName   = make(map[string]*Counter)
+	catalogPatterns []*Counter
+)
+
+func init() {
+	for i := range Catalog {
+		c := &Catalog[i]
+		if c.IsPattern() {
+			catalogPatterns = append(catalogPatterns, c)
+		} else {
+			catalogByName[c.Name] = c
+		}
+	}
+}
+
+// Catalog is the list of well-known HotSpot performance counters.
+// It is used to generate fields.yml and the index template, so keep it in
+// sync with them by `make catalog`.
+var Catalog = []Counter{
+	// java.ci
+	{"java/ci/totalTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Total time spent in JIT compilation."},
+
+	// java.cls
+	{"java/cls/loadedClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of classes loaded."},
+	{"java/cls/unloadedClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of classes unloaded."},
+	{"java/cls/sharedLoadedClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of classes loaded from the shared archive (CDS)."},
+	{"java/cls/sharedUnloadedClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of shared classes unloaded."},
+
+	// java.property
+	{"java/property/**", TypeString, UnitsString, VariabilityConstant, "Java system property."},
+
+	// java.rt
+	{"java/rt/vmArgs", TypeString, UnitsString, VariabilityConstant, "JVM arguments."},
+	{"java/rt/vmFlags", TypeString, UnitsString, VariabilityConstant, "JVM flags which are passed via .hotspotrc or -XX:Flags."},
+
+	// java.threads
+	{"java/threads/daemon", TypeLong, UnitsEvents, VariabilityVariable, "Number of live daemon threads."},
+	{"java/threads/live", TypeLong, UnitsEvents, VariabilityVariable, "Number of live threads."},
+	{"java/threads/livePeak", TypeLong, UnitsEvents, VariabilityVariable, "Peak number of live threads."},
+	{"java/threads/started", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of threads started."},
+
+	// sun.ci
+	{"sun/ci/compilerThread/*/compiles", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of compilations by the compiler thread."},
+	{"sun/ci/compilerThread/*/method", TypeString, UnitsString, VariabilityVariable, "Method which is being compiled by the compiler thread."},
+	{"sun/ci/compilerThread/*/time", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in compilation by the compiler thread."},
+	{"sun/ci/compilerThread/*/type", TypeLong, UnitsNone, VariabilityVariable, "Type of the current compilation by the compiler thread."},
+	{"sun/ci/lastFailedMethod", TypeString, UnitsString, VariabilityVariable, "Last method which failed to be compiled."},
+	{"sun/ci/lastFailedType", TypeLong, UnitsNone, VariabilityVariable, "Type of the last failed compilation."},
+	{"sun/ci/lastInvalidatedMethod", TypeString, UnitsString, VariabilityVariable, "Last method which was invalidated."},
+	{"sun/ci/lastInvalidatedType", TypeLong, UnitsNone, VariabilityVariable, "Type of the last invalidated compilation."},
+	{"sun/ci/lastMethod", TypeString, UnitsString, VariabilityVariable, "Last method which was compiled."},
+	{"sun/ci/lastSize", TypeLong, UnitsBytes, VariabilityVariable, "Bytecode size of the last compiled method."},
+	{"sun/ci/lastType", TypeLong, UnitsNone, VariabilityVariable, "Type of the last compilation."},
+	{"sun/ci/nmethodCodeSize", TypeLong, UnitsBytes, VariabilityVariable, "Code size of compiled methods."},
+	{"sun/ci/nmethodSize", TypeLong, UnitsBytes, VariabilityVariable, "Size of compiled methods including metadata."},
+	{"sun/ci/osrBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytecode size of OSR compiled methods."},
+	{"sun/ci/osrCompiles", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of OSR compilations."},
+	{"sun/ci/osrTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in OSR compilation."},
+	{"sun/ci/standardBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytecode size of standard compiled methods."},
+	{"sun/ci/standardCompiles", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of standard compilations."},
+	{"sun/ci/standardTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in standard compilation."},
+	{"sun/ci/threads", TypeLong, UnitsEvents, VariabilityConstant, "Number of compiler threads."},
+	{"sun/ci/totalBailouts", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of bailed out compilations."},
+	{"sun/ci/totalCompiles", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of compilations."},
+	{"sun/ci/totalInvalidates", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of invalidated compilations."},
+
+	// sun.cls
+	{"sun/cls/appClassBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of application classes loaded."},
+	{"sun/cls/appClassLoadCount", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of application classes loaded."},
+	{"sun/cls/appClassLoadTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in loading application classes."},
+	{"sun/cls/classInitTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in class initialization."},
+	{"sun/cls/classInitTime/self", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in class initialization excluding nested events."},
+	{"sun/cls/classLinkedTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in class linking."},
+	{"sun/cls/classLinkedTime/self", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in class linking excluding nested events."},
+	{"sun/cls/classVerifyTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in class verification."},
+	{"sun/cls/classVerifyTime/self", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in class verification excluding nested events."},
+	{"sun/cls/defineAppClassTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in defining application classes."},
+	{"sun/cls/defineAppClassTime/self", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in defining application classes excluding nested events."},
+	{"sun/cls/defineAppClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of application classes defined."},
+	{"sun/cls/initializedClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of classes initialized."},
+	{"sun/cls/linkedClasses", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of classes linked."},
+	{"sun/cls/loadedBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of classes loaded."},
+	{"sun/cls/lookupSysClassTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in looking up system classes."},
+	{"sun/cls/methodBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of methods loaded."},
+	{"sun/cls/parseClassTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in parsing class files."},
+	{"sun/cls/parseClassTime/self", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in parsing class files excluding nested events."},
+	{"sun/cls/sharedClassLoadTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in loading shared classes."},
+	{"sun/cls/sharedLoadedBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of shared classes loaded."},
+	{"sun/cls/sharedUnloadedBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of shared classes unloaded."},
+	{"sun/cls/sysClassBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of system classes loaded."},
+	{"sun/cls/sysClassLoadTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in loading system classes."},
+	{"sun/cls/time", TypeLong, UnitsTicks, VariabilityMonotonic, "Total time spent in class loading."},
+	{"sun/cls/unloadedBytes", TypeLong, UnitsBytes, VariabilityMonotonic, "Bytes of classes unloaded."},
+	{"sun/classloader/findClassTime", TypeLong, UnitsNanoseconds, VariabilityVariable, "Time spent in ClassLoader.findClass()."},
+	{"sun/classloader/findClasses", TypeLong, UnitsEvents, VariabilityVariable, "Number of ClassLoader.findClass() calls."},
+	{"sun/classloader/parentDelegationTime", TypeLong, UnitsNanoseconds, VariabilityVariable, "Time spent in delegating to the parent class loader."},
+	{"sun/urlClassLoader/readClassBytesTime", TypeLong, UnitsNanoseconds, VariabilityVariable, "Time spent in reading class bytes by URLClassLoader."},
+
+	// sun.gc
+	{"sun/gc/cause", TypeString, UnitsString, VariabilityVariable, "Cause of the current GC."},
+	{"sun/gc/lastCause", TypeString, UnitsString, VariabilityVariable, "Cause of the last GC."},
+	{"sun/gc/collector/*/invocations", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of collections by the collector."},
+	{"sun/gc/collector/*/lastEntryTime", TypeLong, UnitsTicks, VariabilityVariable, "Start time of the last collection by the collector."},
+	{"sun/gc/collector/*/lastExitTime", TypeLong, UnitsTicks, VariabilityVariable, "End time of the last collection by the collector."},
+	{"sun/gc/collector/*/name", TypeString, UnitsString, VariabilityConstant, "Name of the collector."},
+	{"sun/gc/collector/*/time", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in collections by the collector."},
+	{"sun/gc/compressedclassspace/capacity", TypeLong, UnitsBytes, VariabilityVariable, "Committed size of the compressed class space."},
+	{"sun/gc/compressedclassspace/maxCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Maximum size of the compressed class space."},
+	{"sun/gc/compressedclassspace/minCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Minimum size of the compressed class space."},
+	{"sun/gc/compressedclassspace/used", TypeLong, UnitsBytes, VariabilityVariable, "Used size of the compressed class space."},
+	{"sun/gc/generation/*/agetable/bytes/*", TypeLong, UnitsBytes, VariabilityVariable, "Bytes of objects at the age."},
+	{"sun/gc/generation/*/agetable/size", TypeLong, UnitsNone, VariabilityConstant, "Size of the age table."},
+	{"sun/gc/generation/*/capacity", TypeLong, UnitsBytes, VariabilityVariable, "Committed size of the generation."},
+	{"sun/gc/generation/*/maxCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Maximum size of the generation."},
+	{"sun/gc/generation/*/minCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Minimum size of the generation."},
+	{"sun/gc/generation/*/name", TypeString, UnitsString, VariabilityConstant, "Name of the generation."},
+	{"sun/gc/generation/*/spaces", TypeLong, UnitsNone, VariabilityConstant, "Number of spaces in the generation."},
+	{"sun/gc/generation/*/space/*/capacity", TypeLong, UnitsBytes, VariabilityVariable, "Committed size of the space."},
+	{"sun/gc/generation/*/space/*/initCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Initial size of the space."},
+	{"sun/gc/generation/*/space/*/maxCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Maximum size of the space."},
+	{"sun/gc/generation/*/space/*/name", TypeString, UnitsString, VariabilityConstant, "Name of the space."},
+	{"sun/gc/generation/*/space/*/used", TypeLong, UnitsBytes, VariabilityVariable, "Used size of the space."},
+	{"sun/gc/metaspace/capacity", TypeLong, UnitsBytes, VariabilityVariable, "Committed size of the metaspace."},
+	{"sun/gc/metaspace/maxCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Maximum size of the metaspace."},
+	{"sun/gc/metaspace/minCapacity", TypeLong, UnitsBytes, VariabilityConstant, "Minimum size of the metaspace."},
+	{"sun/gc/metaspace/used", TypeLong, UnitsBytes, VariabilityVariable, "Used size of the metaspace."},
+	{"sun/gc/policy/collectors", TypeLong, UnitsNone, VariabilityConstant, "Number of collectors."},
+	{"sun/gc/policy/desiredSurvivorSize", TypeLong, UnitsBytes, VariabilityVariable, "Desired survivor size."},
+	{"sun/gc/policy/generations", TypeLong, UnitsNone, VariabilityConstant, "Number of generations."},
+	{"sun/gc/policy/maxTenuringThreshold", TypeLong, UnitsNone, VariabilityConstant, "Maximum tenuring threshold."},
+	{"sun/gc/policy/name", TypeString, UnitsString, VariabilityConstant, "Name of the GC policy."},
+	{"sun/gc/policy/tenuringThreshold", TypeLong, UnitsNone, VariabilityVariable, "Current tenuring threshold."},
+	{"sun/gc/tlab/alloc", TypeLong, UnitsNone, VariabilityVariable, "Words allocated in TLABs at the last GC."},
+	{"sun/gc/tlab/allocThreads", TypeLong, UnitsNone, VariabilityVariable, "Number of threads which allocated in TLABs."},
+	{"sun/gc/tlab/fastWaste", TypeLong, UnitsNone, VariabilityVariable, "Words wasted by fast refills of TLABs."},
+	{"sun/gc/tlab/fills", TypeLong, UnitsNone, VariabilityVariable, "Number of TLAB refills."},
+	{"sun/gc/tlab/gcWaste", TypeLong, UnitsNone, VariabilityVariable, "Words wasted in TLABs at GC."},
+	{"sun/gc/tlab/maxFastWaste", TypeLong, UnitsNone, VariabilityVariable, "Maximum words wasted by fast refills of TLABs."},
+	{"sun/gc/tlab/maxFills", TypeLong, UnitsNone, VariabilityVariable, "Maximum number of TLAB refills by a thread."},
+	{"sun/gc/tlab/maxGcWaste", TypeLong, UnitsNone, VariabilityVariable, "Maximum words wasted in TLABs at GC by a thread."},
+	{"sun/gc/tlab/maxSlowAlloc", TypeLong, UnitsNone, VariabilityVariable, "Maximum number of slow allocations by a thread."},
+	{"sun/gc/tlab/maxSlowWaste", TypeLong, UnitsNone, VariabilityVariable, "Maximum words wasted by slow refills of TLABs by a thread."},
+	{"sun/gc/tlab/slowAlloc", TypeLong, UnitsNone, VariabilityVariable, "Number of slow allocations outside of TLABs."},
+	{"sun/gc/tlab/slowWaste", TypeLong, UnitsNone, VariabilityVariable, "Words wasted by slow refills of TLABs."},
+
+	// sun.os
+	{"sun/os/hrt/frequency", TypeLong, UnitsHertz, VariabilityConstant, "Frequency of the high resolution timer. Ticks are divided by it to get seconds."},
+	{"sun/os/hrt/ticks", TypeLong, UnitsTicks, VariabilityVariable, "Ticks of the high resolution timer since JVM start."},
+
+	// sun.property
+	{"sun/property/**", TypeString, UnitsString, VariabilityConstant, "System property which is set by the JVM."},
+
+	// sun.rt
+	{"sun/rt/_sync_ContendedLockAttempts", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of contended monitor lock attempts."},
+	{"sun/rt/_sync_Deflations", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitor deflations."},
+	{"sun/rt/_sync_EmptyNotifications", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of notifications without waiters."},
+	{"sun/rt/_sync_FailedSpins", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of failed spins on monitors."},
+	{"sun/rt/_sync_FutileWakeups", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of futile wakeups on monitors."},
+	{"sun/rt/_sync_Inflations", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitor inflations."},
+	{"sun/rt/_sync_MonExtant", TypeLong, UnitsEvents, VariabilityVariable, "Number of extant monitors."},
+	{"sun/rt/_sync_MonInCirculation", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitors in circulation."},
+	{"sun/rt/_sync_MonScavenged", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitors scavenged."},
+	{"sun/rt/_sync_Notifications", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitor notifications."},
+	{"sun/rt/_sync_Parks", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of thread parks on monitors."},
+	{"sun/rt/_sync_PrivateA", TypeLong, UnitsEvents, VariabilityMonotonic, "Internal monitor counter A."},
+	{"sun/rt/_sync_PrivateB", TypeLong, UnitsEvents, VariabilityMonotonic, "Internal monitor counter B."},
+	{"sun/rt/_sync_SlowEnter", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitor enters in the slow path."},
+	{"sun/rt/_sync_SlowExit", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of monitor exits in the slow path."},
+	{"sun/rt/_sync_SlowNotify", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of notify() in the slow path."},
+	{"sun/rt/_sync_SlowNotifyAll", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of notifyAll() in the slow path."},
+	{"sun/rt/_sync_SuccessfulSpins", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of successful spins on monitors."},
+	{"sun/rt/applicationTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in the application, i.e. outside of safepoints."},
+	{"sun/rt/createVmBeginTime", TypeLong, UnitsNone, VariabilityVariable, "Time (milliseconds since epoch) when JVM creation began."},
+	{"sun/rt/createVmEndTime", TypeLong, UnitsNone, VariabilityVariable, "Time (milliseconds since epoch) when JVM creation ended."},
+	{"sun/rt/internalVersion", TypeString, UnitsString, VariabilityConstant, "Internal version string of the JVM."},
+	{"sun/rt/javaCommand", TypeString, UnitsString, VariabilityConstant, "Main class (or jar file) and its arguments."},
+	{"sun/rt/jvmCapabilities", TypeString, UnitsString, VariabilityConstant, "Capabilities of the JVM."},
+	{"sun/rt/jvmVersion", TypeLong, UnitsNone, VariabilityConstant, "Version of the JVM as a number."},
+	{"sun/rt/safepointSyncTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in reaching safepoints."},
+	{"sun/rt/safepointTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in safepoints."},
+	{"sun/rt/safepoints", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of safepoints."},
+	{"sun/rt/threadInterruptSignaled", TypeLong, UnitsEvents, VariabilityMonotonic, "Number of signaled thread interrupts."},
+	{"sun/rt/vmInitDoneTime", TypeLong, UnitsNone, VariabilityVariable, "Time (milliseconds since epoch) when JVM initialization was done."},
+
+	// sun.threads
+	{"sun/threads/vmOperationTime", TypeLong, UnitsTicks, VariabilityMonotonic, "Time spent in VM operations."},
+
+	// sun.zip
+	{"sun/zip/zipFile/openTime", TypeLong, UnitsNanoseconds, VariabilityVariable, "Time spent in opening zip files."},
+	{"sun/zip/zipFiles", TypeLong, UnitsEvents, VariabilityVariable, "Number of zip files opened."},
+}
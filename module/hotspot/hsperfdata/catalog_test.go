@@ -0,0 +1,39 @@
+package hsperfdata
+
+import (
+	"testing"
+)
+
+func TestLookupCounter(t *testing.T) {
+	c, ok := LookupCounter("sun/gc/cause")
+	assertEquals(t, true, ok)
+	assertEquals(t, TypeString, c.Type)
+
+	c, ok = LookupCounter("sun.os.hrt.frequency")
+	assertEquals(t, true, ok)
+	assertEquals(t, UnitsHertz, c.Units)
+
+	c, ok = LookupCounter("sun/gc/generation/1/space/0/used")
+	assertEquals(t, true, ok)
+	assertEquals(t, "sun/gc/generation/*/space/*/used", c.Name)
+
+	c, ok = LookupCounter("java/property/java/vm/version")
+	assertEquals(t, true, ok)
+	assertEquals(t, "java/property/**", c.Name)
+
+	_, ok = LookupCounter("java/property")
+	assertEquals(t, false, ok)
+
+	_, ok = LookupCounter("sun/gc/collector/0/time/diff")
+	assertEquals(t, false, ok)
+}
+
+func TestCatalogHasNoDuplicates(t *testing.T) {
+	names := make(map[string]bool)
+	for _, c := range Catalog {
+		if names[c.Name] {
+			t.Errorf("%v is duplicated", c.Name)
+		}
+		names[c.Name] = true
+	}
+}
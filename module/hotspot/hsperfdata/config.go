@@ -0,0 +1,120 @@
+package hsperfdata
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ways to ship the difference of a monotonic counter which goes backwards.
+const (
+	// ResetValue ships the current value as the difference, i.e. the
+	// increase since the reset.
+	ResetValue = "value"
+
+	// ResetNull ships null as the difference and the rate.
+	ResetNull = "null"
+)
+
+// Config holds the configuration of the hotspot module.
+type Config struct {
+	ForceCachedEntries []string `config:"force_collect"`
+	Pid                string   `config:"pid"`
+	DerivedMetrics     bool     `config:"derived_metrics"`
+
+	// Normalize maps sun.gc counters onto the schema which is stable across
+	// JDK versions and garbage collectors.
+	Normalize bool `config:"normalize"`
+
+	// State keeps the state of each JVM across restarts of hsbeat.
+	State StateConfig `config:"state"`
+
+	// CounterReset is how the difference of a monotonic counter which goes
+	// backwards (e.g. the JVM is restarted with the same PID) is shipped:
+	// ResetValue or ResetNull.
+	CounterReset string `config:"counter_reset"`
+
+	// MetricTypes adds the metric type (counter or gauge) of each long
+	// counter to the event.
+	MetricTypes bool `config:"metric_types"`
+
+	// ChangesOnly ships only counters which are changed since the previous
+	// fetch. A full snapshot is shipped every FullSnapshotPeriods fetches or
+	// every FullSnapshotInterval. Zero disables each condition.
+	ChangesOnly          bool          `config:"changes_only"`
+	FullSnapshotPeriods  int           `config:"full_snapshot.periods" validate:"min=0"`
+	FullSnapshotInterval time.Duration `config:"full_snapshot.interval" validate:"min=0"`
+
+	// JVMInfoInterval is the interval to ship the inventory of each JVM
+	// again in the jvminfo metricset. Zero disables it.
+	JVMInfoInterval time.Duration `config:"jvminfo.interval" validate:"min=0"`
+
+	// SamplingInterval is the interval to sample counters between fetches
+	// in the hsperfdata metricset. Variable counters are summarized in
+	// `<counter>/min`, `<counter>/max` and `<counter>/avg`. Counters in
+	// CounterGroups are not sampled. Zero disables it.
+	SamplingInterval time.Duration `config:"sampling.interval" validate:"min=0"`
+
+	// CounterGroups are groups of counters which are read at their own
+	// interval in the hsperfdata metricset instead of every period.
+	CounterGroups []GroupRule `config:"counter_groups"`
+
+	// UnresponsiveThreshold is the time after which a JVM whose hsperfdata
+	// file stops advancing is regarded as unresponsive in the liveness
+	// metricset. Zero disables it.
+	UnresponsiveThreshold time.Duration `config:"unresponsive.threshold" validate:"min=0"`
+
+	// Redaction masks secrets in string counters before they are shipped.
+	Redaction RedactionConfig `config:"redaction"`
+
+	// Labels are rules to extract labels (e.g. service.name) of each JVM
+	// which are added to all events of the JVM.
+	Labels []LabelRule `config:"labels"`
+
+	// Counters selects counters which are read by a built-in profile and
+	// include/exclude patterns.
+	Counters FilterConfig `config:"counters"`
+}
+
+// DefaultConfig returns the default configuration of the hotspot module.
+func DefaultConfig() Config {
+	return Config{
+		ForceCachedEntries:    []string{},
+		Pid:                   "0",
+		DerivedMetrics:        true,
+		MetricTypes:           false,
+		CounterReset:          ResetValue,
+		Normalize:             true,
+		ChangesOnly:           false,
+		FullSnapshotPeriods:   0,
+		FullSnapshotInterval:  5 * time.Minute,
+		JVMInfoInterval:       time.Hour,
+		SamplingInterval:      0,
+		UnresponsiveThreshold: 5 * time.Second,
+		Redaction: RedactionConfig{
+			Enabled:     true,
+			Builtin:     true,
+			Patterns:    []string{},
+			Replacement: "********",
+		},
+		Labels:        []LabelRule{},
+		CounterGroups: []GroupRule{},
+		State: StateConfig{
+			Enabled:       false,
+			Path:          "hotspot.state",
+			FlushInterval: 10 * time.Second,
+			TTL:           time.Hour,
+		},
+		Counters: FilterConfig{
+			Include: []string{},
+			Exclude: []string{},
+		},
+	}
+}
+
+// Validate checks options which cannot be checked by tags.
+func (c *Config) Validate() error {
+	if c.CounterReset != ResetValue && c.CounterReset != ResetNull {
+		return fmt.Errorf("counter_reset must be %v or %v: %v", ResetValue, ResetNull, c.CounterReset)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package hsperfdata
+
+import (
+	"strconv"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Names of the counters which are used to calculate derived metrics.
+const (
+	hrtTicks        = "sun/os/hrt/ticks"
+	hrtFrequency    = "sun/os/hrt/frequency"
+	applicationTime = "sun/rt/applicationTime"
+	safepointTime   = "sun/rt/safepointTime"
+	compileTime     = "java/ci/totalTime"
+	metaspaceUsed   = "sun/gc/metaspace/used"
+	metaspaceCap    = "sun/gc/metaspace/capacity"
+	metaspaceMax    = "sun/gc/metaspace/maxCapacity"
+)
+
+// youngGen and oldGen are the generation indices used by HotSpot for the
+// Java heap. The metaspace (or the PermGen in JDK 7) is not included.
+const (
+	youngGen = 0
+	oldGen   = 1
+)
+
+// minorCollector and majorCollector are the collector indices which HotSpot
+// uses for young and full collections.
+const (
+	minorCollector = 0
+	majorCollector = 1
+)
+
+// sample holds long counter values of two consecutive fetches and the time
+// between them.
+type sample struct {
+	current  map[string]int64
+	previous map[string]int64
+	seconds  float64 // elapsed time between previous and current in seconds
+}
+
+// delta returns the difference of the counter between two fetches.
+// It returns false if the counter is not available in both fetches.
+func (s *sample) delta(name string) (int64, bool) {
+	cur, ok := s.current[name]
+	if !ok {
+		return 0, false
+	}
+	prev, ok := s.previous[name]
+	if !ok {
+		return 0, false
+	}
+	return cur - prev, true
+}
+
+// timeShare returns the share of the elapsed time which is spent in the tick
+// counter. The result is a fraction between 0 and 1.
+func (s *sample) timeShare(name string) (float64, bool) {
+	freq := s.current[hrtFrequency]
+	if freq <= 0 || s.seconds <= 0 {
+		return 0, false
+	}
+	delta, ok := s.delta(name)
+	if !ok || delta < 0 {
+		return 0, false
+	}
+	return float64(delta) / float64(freq) / s.seconds, true
+}
+
+func genCounter(gen int, name string) string {
+	return "sun/gc/generation/" + strconv.Itoa(gen) + "/" + name
+}
+
+func spaceCounter(gen int, space int, name string) string {
+	return "sun/gc/generation/" + strconv.Itoa(gen) + "/space/" + strconv.Itoa(space) + "/" + name
+}
+
+// CollectorCounter returns the name of the counter of the collector.
+func CollectorCounter(collector int, name string) string {
+	return "sun/gc/collector/" + strconv.Itoa(collector) + "/" + name
+}
+
+// GenerationUsed returns the sum of the used bytes of all spaces in the
+// generation.
+func GenerationUsed(values map[string]int64, gen int) (int64, bool) {
+	var used int64
+	found := false
+	for space := 0; ; space++ {
+		v, ok := values[spaceCounter(gen, space, "used")]
+		if !ok {
+			break
+		}
+		used += v
+		found = true
+	}
+	return used, found
+}
+
+// usage builds used, committed and max fields of a memory area.
+func usage(used, committed, max int64) common.MapStr {
+	result := common.MapStr{
+		"used":      common.MapStr{"bytes": used},
+		"committed": common.MapStr{"bytes": committed},
+		"max":       common.MapStr{"bytes": max},
+	}
+	if committed > 0 {
+		result["used"].(common.MapStr)["pct"] = float64(used) / float64(committed)
+	}
+	if max > 0 {
+		result["used"].(common.MapStr)["max_pct"] = float64(used) / float64(max)
+	}
+	return result
+}
+
+func heapUsage(values map[string]int64) (common.MapStr, bool) {
+	var used, committed, max int64
+	for _, gen := range []int{youngGen, oldGen} {
+		genUsed, ok := GenerationUsed(values, gen)
+		if !ok {
+			return nil, false
+		}
+		used += genUsed
+		committed += values[genCounter(gen, "capacity")]
+		max += values[genCounter(gen, "maxCapacity")]
+	}
+	return usage(used, committed, max), true
+}
+
+func metaspaceUsage(values map[string]int64) (common.MapStr, bool) {
+	used, ok := values[metaspaceUsed]
+	if !ok {
+		return nil, false
+	}
+	return usage(used, values[metaspaceCap], values[metaspaceMax]), true
+}
+
+func (s *sample) gcTimeShare() (common.MapStr, bool) {
+	result := common.MapStr{}
+	var total float64
+	found := false
+	for name := range s.current {
+		collector, ok := collectorIndex(name, "time")
+		if !ok {
+			continue
+		}
+		share, ok := s.timeShare(name)
+		if !ok {
+			continue
+		}
+		total += share
+		found = true
+		switch collector {
+		case minorCollector:
+			result["minor"] = common.MapStr{"time": common.MapStr{"pct": share}}
+		case majorCollector:
+			result["major"] = common.MapStr{"time": common.MapStr{"pct": share}}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	result["time"] = common.MapStr{"pct": total}
+	return result, true
+}
+
+// allocationRate estimates bytes per second which are allocated in eden.
+// If minor GCs happen between two fetches, eden is assumed to be full at
+// each of them.
+func (s *sample) allocationRate() (float64, bool) {
+	if s.seconds <= 0 {
+		return 0, false
+	}
+	edenUsed := spaceCounter(youngGen, 0, "used")
+	edenCapacity := spaceCounter(youngGen, 0, "capacity")
+	gcs, ok := s.delta(CollectorCounter(minorCollector, "invocations"))
+	if !ok || gcs < 0 {
+		return 0, false
+	}
+	cur, ok := s.current[edenUsed]
+	if !ok {
+		return 0, false
+	}
+	prev, ok := s.previous[edenUsed]
+	if !ok {
+		return 0, false
+	}
+
+	var allocated int64
+	if gcs == 0 {
+		allocated = cur - prev
+	} else {
+		allocated = s.previous[edenCapacity] - prev + cur + (gcs-1)*s.current[edenCapacity]
+	}
+	if allocated < 0 {
+		return 0, false
+	}
+	return float64(allocated) / s.seconds, true
+}
+
+// promotionRate estimates bytes per second which are promoted to the old
+// generation by minor GCs. It is not available when a major GC happens
+// between two fetches because the old generation shrinks at that time.
+func (s *sample) promotionRate() (float64, bool) {
+	if s.seconds <= 0 {
+		return 0, false
+	}
+	if majors, ok := s.delta(CollectorCounter(majorCollector, "invocations")); !ok || majors != 0 {
+		return 0, false
+	}
+	minors, ok := s.delta(CollectorCounter(minorCollector, "invocations"))
+	if !ok || minors <= 0 {
+		return 0, true
+	}
+	cur, ok := GenerationUsed(s.current, oldGen)
+	if !ok {
+		return 0, false
+	}
+	prev, ok := GenerationUsed(s.previous, oldGen)
+	if !ok {
+		return 0, false
+	}
+	if cur < prev {
+		return 0, true
+	}
+	return float64(cur-prev) / s.seconds, true
+}
+
+// computeDerived calculates metrics which are derived from raw counters.
+// Metrics which need the previous fetch are omitted at the first fetch.
+func (s *sample) computeDerived() common.MapStr {
+	derived := common.MapStr{}
+
+	if heap, ok := heapUsage(s.current); ok {
+		derived["heap"] = heap
+	}
+	if metaspace, ok := metaspaceUsage(s.current); ok {
+		derived["metaspace"] = metaspace
+	}
+
+	if s.previous == nil {
+		return derived
+	}
+
+	if gc, ok := s.gcTimeShare(); ok {
+		derived["gc"] = gc
+	}
+	if share, ok := s.timeShare(safepointTime); ok {
+		derived["safepoint"] = common.MapStr{"time": common.MapStr{"pct": share}}
+	}
+	if share, ok := s.timeShare(applicationTime); ok {
+		derived["application"] = common.MapStr{"time": common.MapStr{"pct": share}}
+	}
+	if share, ok := s.timeShare(compileTime); ok {
+		derived["compilation"] = common.MapStr{"time": common.MapStr{"pct": share}}
+	}
+	if rate, ok := s.allocationRate(); ok {
+		derived["allocation"] = common.MapStr{"bytes_per_sec": rate}
+	}
+	if rate, ok := s.promotionRate(); ok {
+		derived["promotion"] = common.MapStr{"bytes_per_sec": rate}
+	}
+
+	return derived
+}
@@ -0,0 +1,117 @@
+package hsperfdata
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func baseCounters() map[string]int64 {
+	return map[string]int64{
+		hrtFrequency:                           1000,
+		hrtTicks:                               10000,
+		"sun/gc/generation/0/capacity":         300,
+		"sun/gc/generation/0/maxCapacity":      600,
+		"sun/gc/generation/0/space/0/used":     100,
+		"sun/gc/generation/0/space/0/capacity": 200,
+		"sun/gc/generation/0/space/1/used":     50,
+		"sun/gc/generation/1/capacity":         700,
+		"sun/gc/generation/1/maxCapacity":      1400,
+		"sun/gc/generation/1/space/0/used":     350,
+		"sun/gc/collector/0/invocations":       10,
+		"sun/gc/collector/0/time":              400,
+		"sun/gc/collector/1/invocations":       1,
+		"sun/gc/collector/1/time":              100,
+		safepointTime:                          500,
+		applicationTime:                        9000,
+		compileTime:                            300,
+		metaspaceUsed:                          40,
+		metaspaceCap:                           80,
+		metaspaceMax:                           160,
+	}
+}
+
+func TestHeapUsage(t *testing.T) {
+	s := sample{current: baseCounters()}
+	derived := s.computeDerived()
+
+	assertEquals(t, int64(500), getValue(t, derived, "heap.used.bytes"))
+	assertEquals(t, int64(1000), getValue(t, derived, "heap.committed.bytes"))
+	assertEquals(t, int64(2000), getValue(t, derived, "heap.max.bytes"))
+	assertEquals(t, 0.5, getValue(t, derived, "heap.used.pct"))
+	assertEquals(t, 0.25, getValue(t, derived, "heap.used.max_pct"))
+	assertEquals(t, 0.5, getValue(t, derived, "metaspace.used.pct"))
+}
+
+func TestFirstFetchHasNoTimeShare(t *testing.T) {
+	s := sample{current: baseCounters()}
+	derived := s.computeDerived()
+
+	for _, key := range []string{"gc", "safepoint", "allocation", "promotion"} {
+		if _, exists := derived[key]; exists {
+			t.Errorf("%v should not be calculated at the first fetch", key)
+		}
+	}
+}
+
+func TestTimeShare(t *testing.T) {
+	prev := baseCounters()
+	cur := baseCounters()
+	cur[hrtTicks] += 2000
+	cur["sun/gc/collector/0/time"] += 200
+	cur["sun/gc/collector/1/time"] += 100
+	cur[safepointTime] += 400
+	cur[compileTime] += 20
+
+	s := sample{current: cur, previous: prev, seconds: 2}
+	derived := s.computeDerived()
+
+	assertEquals(t, 0.1, getValue(t, derived, "gc.minor.time.pct"))
+	assertEquals(t, 0.05, getValue(t, derived, "gc.major.time.pct"))
+	assertEquals(t, 0.15000000000000002, getValue(t, derived, "gc.time.pct"))
+	assertEquals(t, 0.2, getValue(t, derived, "safepoint.time.pct"))
+	assertEquals(t, 0.01, getValue(t, derived, "compilation.time.pct"))
+}
+
+func TestAllocationAndPromotionRate(t *testing.T) {
+	prev := baseCounters()
+	cur := baseCounters()
+	cur["sun/gc/collector/0/invocations"] += 2
+	cur["sun/gc/generation/0/space/0/used"] = 30
+	cur["sun/gc/generation/1/space/0/used"] += 80
+
+	s := sample{current: cur, previous: prev, seconds: 2}
+	derived := s.computeDerived()
+
+	// (200 - 100) remaining eden + 30 after the last GC + 200 for the second GC
+	assertEquals(t, 165.0, getValue(t, derived, "allocation.bytes_per_sec"))
+	assertEquals(t, 40.0, getValue(t, derived, "promotion.bytes_per_sec"))
+}
+
+func TestPromotionRateIsOmittedAtMajorGC(t *testing.T) {
+	prev := baseCounters()
+	cur := baseCounters()
+	cur["sun/gc/collector/0/invocations"] += 1
+	cur["sun/gc/collector/1/invocations"] += 1
+
+	s := sample{current: cur, previous: prev, seconds: 1}
+	derived := s.computeDerived()
+
+	if _, exists := derived["promotion"]; exists {
+		t.Errorf("promotion rate should not be calculated when major GC happens")
+	}
+}
+
+func getValue(t *testing.T, m common.MapStr, key string) interface{} {
+	v, err := m.GetValue(key)
+	if err != nil {
+		t.Fatalf("could not get %v: %v", key, err)
+	}
+	return v
+}
+
+func assertEquals(t *testing.T, expected interface{}, actual interface{}) {
+	if expected != actual {
+		t.Errorf("%v is not equal to %v", expected, actual)
+	}
+}
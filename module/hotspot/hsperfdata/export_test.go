@@ -0,0 +1,10 @@
+package hsperfdata
+
+// Normalize exports normalize for tests which read hsperfdata files.
+var Normalize = normalize
+
+// ReadAllEntries and IsTorn export methods to read a file torn by the JVM.
+var (
+	ReadAllEntries = (*Process).readAllEntries
+	IsTorn         = (*Process).isTorn
+)
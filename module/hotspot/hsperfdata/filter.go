@@ -0,0 +1,214 @@
+package hsperfdata
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Built-in counter profiles.
+const (
+	ProfileMinimal  = "minimal"
+	ProfileStandard = "standard"
+	ProfileFull     = "full"
+)
+
+// profiles are prefixes of counters in each built-in profile. The full
+// profile selects all counters.
+var profiles = map[string][]string{
+	ProfileMinimal: {
+		"sun/gc/",
+	},
+	ProfileStandard: {
+		"sun/gc/",
+		"java/threads/",
+		"sun/threads/",
+		"java/cls/",
+		"sun/cls/",
+		"sun/classloader/",
+		"sun/rt/",
+	},
+	ProfileFull: nil,
+}
+
+// FilterConfig selects counters which are read after the first read.
+// Constants are always read at the first read because they describe the JVM.
+type FilterConfig struct {
+	// Profile is a built-in set of counters: minimal (heap and GC), standard
+	// (minimal plus threads, class loading and safepoints) or full (all).
+	Profile string `config:"profile"`
+
+	// Include adds counters to the profile. If no profile is given, only
+	// included counters are read. Exclude removes counters, and it wins
+	// over Profile and Include.
+	//
+	// Patterns are prefixes (e.g. sun.gc.), globs (e.g. sun.gc.collector.*.time,
+	// where `*` matches an element and `**` matches the rest) or regular
+	// expressions against slashed names with the prefix `regexp:`.
+	Include []string `config:"include"`
+	Exclude []string `config:"exclude"`
+}
+
+// Validate checks that the profile is known and all patterns are valid.
+func (c *FilterConfig) Validate() error {
+	if _, exists := profiles[c.Profile]; c.Profile != "" && !exists {
+		return fmt.Errorf("unknown counter profile %v (expected %v, %v or %v)", c.Profile, ProfileMinimal, ProfileStandard, ProfileFull)
+	}
+	for _, patterns := range [][]string{c.Include, c.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := compileCounterPattern(pattern); err != nil {
+				return fmt.Errorf("invalid counter pattern %v: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// counterMatcher matches a slashed counter name.
+type counterMatcher func(name string) bool
+
+func compileCounterPattern(pattern string) (counterMatcher, error) {
+	if strings.HasPrefix(pattern, "regexp:") {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "regexp:"))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	pattern = strings.Replace(pattern, ".", "/", -1)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return func(name string) bool {
+			return strings.HasPrefix(name, pattern)
+		}, nil
+	}
+
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// globToRegexp converts the glob to a regular expression. `*` and `?` do
+// not match '/', and `**` matches anything.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func compileCounterPatterns(patterns []string) []counterMatcher {
+	matchers := make([]counterMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		m, err := compileCounterPattern(pattern)
+		if err != nil {
+			continue // rejected by Validate
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers
+}
+
+func matchAny(matchers []counterMatcher, name string) bool {
+	for _, m := range matchers {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CounterFilter selects modifiable counters which are read after the first
+// read. The result of each counter is cached. It is shared by all processes
+// of a Tracker, which may be read concurrently by the sampling goroutine.
+type CounterFilter struct {
+	all     bool // the profile selects all counters
+	include []counterMatcher
+	exclude []counterMatcher
+
+	mu    sync.Mutex // guards cache
+	cache map[string]bool
+}
+
+// NewCounterFilter creates a CounterFilter for the config, which must be
+// validated in advance. It returns nil if all counters are selected.
+func NewCounterFilter(config *FilterConfig) *CounterFilter {
+	profile := config.Profile
+	if profile == "" && len(config.Include) == 0 {
+		profile = ProfileFull
+	}
+	if profile == ProfileFull && len(config.Exclude) == 0 {
+		return nil
+	}
+
+	f := &CounterFilter{
+		all:     profile == ProfileFull,
+		include: compileCounterPatterns(append(profiles[profile], config.Include...)),
+		exclude: compileCounterPatterns(config.Exclude),
+		cache:   make(map[string]bool),
+	}
+	return f
+}
+
+// Match returns true if the counter is selected. sun.os.hrt counters are
+// always selected because they are needed to calculate rates, and
+// sun.rt.createVmBeginTime because it identifies the JVM. All counters are
+// selected if f is nil.
+func (f *CounterFilter) Match(name string) bool {
+	if f == nil || name == hrtTicks || name == hrtFrequency || name == createVMBeginTime {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if matched, exists := f.cache[name]; exists {
+		return matched
+	}
+
+	matched := (f.all || matchAny(f.include, name)) && !matchAny(f.exclude, name)
+	f.cache[name] = matched
+	return matched
+}
+
+// FilterEntries returns entries which are constants or selected by f.
+func (f *CounterFilter) FilterEntries(entries []PerfDataEntry) []PerfDataEntry {
+	if f == nil {
+		return entries
+	}
+	result := entries[:0]
+	for _, entry := range entries {
+		if entry.DataVariability == VariabilityConstant || f.Match(entry.EntryName) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package hsperfdata
+
+import (
+	"testing"
+)
+
+func TestCounterPattern(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"sun.gc.", "sun/gc/collector/0/time", true},
+		{"sun/gc/", "sun/gc/collector/0/time", true},
+		{"sun.gc.", "sun/gcx", false},
+		{"sun.gc.collector.*.time", "sun/gc/collector/1/time", true},
+		{"sun.gc.collector.*.time", "sun/gc/collector/1/lastEntryTime", false},
+		{"sun.gc.*", "sun/gc/collector/1/time", false},
+		{"sun.gc.**", "sun/gc/collector/1/time", true},
+		{"sun.gc.generation.[01].space.?.used", "sun/gc/generation/1/space/0/used", true},
+		{"sun.gc.generation.[!01].space.?.used", "sun/gc/generation/1/space/0/used", false},
+		{`regexp:^sun/(ci|cls)/`, "sun/cls/time", true},
+		{`regexp:^sun/(ci|cls)/`, "sun/classloader/findClassTime", false},
+	} {
+		m, err := compileCounterPattern(c.pattern)
+		if err != nil {
+			t.Fatalf("%v: %v", c.pattern, err)
+		}
+		if m(c.name) != c.matched {
+			t.Errorf("%v should match %v: %v", c.pattern, c.name, c.matched)
+		}
+	}
+}
+
+func TestCounterProfiles(t *testing.T) {
+	minimal := NewCounterFilter(&FilterConfig{Profile: ProfileMinimal})
+	assertEquals(t, true, minimal.Match("sun/gc/generation/1/space/0/used"))
+	assertEquals(t, true, minimal.Match("sun/os/hrt/ticks"))
+	assertEquals(t, true, minimal.Match("sun/rt/createVmBeginTime"))
+	assertEquals(t, false, minimal.Match("java/threads/live"))
+
+	standard := NewCounterFilter(&FilterConfig{Profile: ProfileStandard})
+	assertEquals(t, true, standard.Match("java/threads/live"))
+	assertEquals(t, true, standard.Match("sun/rt/safepointTime"))
+	assertEquals(t, true, standard.Match("sun/cls/time"))
+	assertEquals(t, false, standard.Match("sun/ci/totalTime"))
+
+	if NewCounterFilter(&FilterConfig{Profile: ProfileFull}) != nil {
+		t.Errorf("full profile should not filter counters")
+	}
+	if NewCounterFilter(&FilterConfig{}) != nil {
+		t.Errorf("empty config should not filter counters")
+	}
+}
+
+func TestIncludeExclude(t *testing.T) {
+	f := NewCounterFilter(&FilterConfig{
+		Profile: ProfileMinimal,
+		Include: []string{"sun.ci."},
+		Exclude: []string{"sun.gc.tlab."},
+	})
+	assertEquals(t, true, f.Match("sun/ci/totalTime"))
+	assertEquals(t, true, f.Match("sun/gc/collector/0/time"))
+	assertEquals(t, false, f.Match("sun/gc/tlab/alloc"))
+
+	// only included counters without a profile
+	f = NewCounterFilter(&FilterConfig{Include: []string{"sun.ci."}})
+	assertEquals(t, true, f.Match("sun/ci/totalTime"))
+	assertEquals(t, false, f.Match("sun/gc/collector/0/time"))
+
+	// exclusion from all counters
+	f = NewCounterFilter(&FilterConfig{Exclude: []string{"sun.zip.**"}})
+	assertEquals(t, true, f.Match("sun/ci/totalTime"))
+	assertEquals(t, false, f.Match("sun/zip/zipFiles"))
+}
+
+func TestFilterEntries(t *testing.T) {
+	f := NewCounterFilter(&FilterConfig{Profile: ProfileMinimal})
+	entries := f.FilterEntries([]PerfDataEntry{
+		longEntry("sun/gc/collector/0/invocations", 1),
+		longEntry("java/threads/live", 10),
+		{EntryName: "java/property/java/vm/name", DataType: 'B', DataVariability: VariabilityConstant},
+	})
+	assertEquals(t, 2, len(entries))
+	assertEquals(t, "java/property/java/vm/name", entries[1].EntryName)
+}
+
+func TestFilterConfigValidate(t *testing.T) {
+	for _, config := range []FilterConfig{
+		{Profile: "tiny"},
+		{Include: []string{"regexp:("}},
+		{Exclude: []string{"regexp:["}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%v should be rejected", config)
+		}
+	}
+	config := FilterConfig{Profile: ProfileStandard, Include: []string{"sun.ci.*"}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("%v should be accepted: %v", config, err)
+	}
+}
@@ -0,0 +1,262 @@
+package hsperfdata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// GroupRule is a group of counters which are read and shipped at its own
+// interval in the hsperfdata metricset. Counters which do not belong to any
+// group are read at every fetch. Counters of a group are still read at every
+// fetch if other metricsets of the module use them.
+type GroupRule struct {
+	Name string `config:"name"`
+
+	// Prefixes and Pattern select counters of the group. Counter names can
+	// be written with '.' or '/'.
+	Prefixes []string `config:"prefixes"`
+	Pattern  string   `config:"pattern"`
+
+	Interval time.Duration `config:"interval"`
+}
+
+// Validate checks that the group selects counters and has an interval.
+func (r *GroupRule) Validate() error {
+	if len(r.Prefixes) == 0 && r.Pattern == "" {
+		return fmt.Errorf("counter group %v: prefixes or pattern is required", r.Name)
+	}
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("counter group %v: invalid pattern %v: %v", r.Name, r.Pattern, err)
+	}
+	if r.Interval <= 0 {
+		return errors.New("counter group " + r.Name + ": interval must be positive")
+	}
+	return nil
+}
+
+type groupRule struct {
+	prefixes []string
+	pattern  *regexp.Regexp
+	interval time.Duration
+}
+
+// counterGroups finds the group of each counter. The first rule which
+// matches the counter wins. It is not safe for concurrent use, so the
+// Tracker and the hsperfdata metricset have their own counterGroups.
+type counterGroups struct {
+	rules []groupRule
+	cache map[string]int // counter name to the group index, -1 if none
+}
+
+// newCounterGroups creates counterGroups for the rules, which must be
+// validated in advance. It returns nil if there are no rules.
+func newCounterGroups(rules []GroupRule) *counterGroups {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	g := &counterGroups{cache: make(map[string]int)}
+	for _, rule := range rules {
+		r := groupRule{interval: rule.Interval}
+		for _, prefix := range rule.Prefixes {
+			r.prefixes = append(r.prefixes, strings.Replace(prefix, ".", "/", -1))
+		}
+		if rule.Pattern != "" {
+			r.pattern = regexp.MustCompile(rule.Pattern)
+		}
+		g.rules = append(g.rules, r)
+	}
+	return g
+}
+
+// group returns the index of the group of the counter, or -1 if it does not
+// belong to any group. sun.os.hrt counters are always read because they are
+// needed to measure the elapsed time.
+func (g *counterGroups) group(name string) int {
+	if i, exists := g.cache[name]; exists {
+		return i
+	}
+
+	i := -1
+	if name != hrtTicks && name != hrtFrequency {
+	rules:
+		for n, rule := range g.rules {
+			for _, prefix := range rule.prefixes {
+				if strings.HasPrefix(name, prefix) {
+					i = n
+					break rules
+				}
+			}
+			if rule.pattern != nil && rule.pattern.MatchString(name) {
+				i = n
+				break
+			}
+		}
+	}
+	g.cache[name] = i
+	return i
+}
+
+// groupSchedule decides groups of a process which are read at each update
+// of the Tracker.
+type groupSchedule struct {
+	groups   *counterGroups
+	slack    time.Duration // tolerance of the fetch timing
+	id       string        // JVM ID at the last read
+	lastRead []time.Time
+}
+
+func newGroupSchedule(groups *counterGroups, period time.Duration) *groupSchedule {
+	if groups == nil {
+		return nil
+	}
+	return &groupSchedule{
+		groups:   groups,
+		slack:    period / 2,
+		lastRead: make([]time.Time, len(groups.rules)),
+	}
+}
+
+// due returns groups whose interval has elapsed since they were read last
+// time. It returns nil if s is nil.
+func (s *groupSchedule) due(now time.Time) []bool {
+	if s == nil {
+		return nil
+	}
+	due := make([]bool, len(s.groups.rules))
+	for i, rule := range s.groups.rules {
+		last := s.lastRead[i]
+		due[i] = last.IsZero() || now.Sub(last)+s.slack >= rule.interval
+	}
+	return due
+}
+
+// commit records the read time of groups which are read. All groups are read
+// if the JVM is read first time or re-created, so they are marked in read.
+func (s *groupSchedule) commit(id string, read []bool, now time.Time) {
+	if s == nil {
+		return
+	}
+	if id != s.id {
+		for i := range read {
+			read[i] = true
+		}
+		s.id = id
+	}
+	for i, r := range read {
+		if r {
+			s.lastRead[i] = now
+		}
+	}
+}
+
+// groupSelector returns the function which selects counters which do not
+// belong to any group or belong to a group in read. It returns nil if g is
+// nil or read is nil, i.e. all counters are selected.
+func (g *counterGroups) groupSelector(read []bool) func(name string) bool {
+	if g == nil || read == nil {
+		return nil
+	}
+	return func(name string) bool {
+		i := g.group(name)
+		return i < 0 || read[i]
+	}
+}
+
+// groupReads holds sun.os.hrt counters of a process when each group is read
+// last time, to calculate rates of counters in the group.
+type groupReads struct {
+	groups       *counterGroups
+	previous     []map[string]int64
+	previousTime []time.Time
+}
+
+func newGroupReads(groups *counterGroups) *groupReads {
+	if groups == nil {
+		return nil
+	}
+	return &groupReads{
+		groups:       groups,
+		previous:     make([]map[string]int64, len(groups.rules)),
+		previousTime: make([]time.Time, len(groups.rules)),
+	}
+}
+
+// ungrouped returns true if the counter does not belong to any group, i.e.
+// it is read at every fetch. Only such counters are sampled between fetches
+// because samples are summarized per period. It returns true if r is nil.
+func (r *groupReads) ungrouped(name string) bool {
+	return r == nil || r.groups.group(name) < 0
+}
+
+// ungroupedEntries returns entries which do not belong to any group.
+func (r *groupReads) ungroupedEntries(entries []PerfDataEntry) []PerfDataEntry {
+	if r == nil {
+		return entries
+	}
+	result := make([]PerfDataEntry, 0, len(entries))
+	for _, entry := range entries {
+		if r.ungrouped(entry.EntryName) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// elapsed returns the interval of the counter since the previous read of its
+// group. It returns false if the counter does not belong to any group.
+func (r *groupReads) elapsed(name string, current map[string]int64, now time.Time) (Interval, bool) {
+	if r == nil {
+		return Interval{}, false
+	}
+	i := r.groups.group(name)
+	if i < 0 {
+		return Interval{}, false
+	}
+	return Elapsed(current, r.previous[i], now, r.previousTime[i], r.groups.rules[i].interval), true
+}
+
+// commit records sun.os.hrt counters of groups which are read in the
+// snapshot.
+func (r *groupReads) commit(read []bool, current map[string]int64, now time.Time) {
+	if r == nil {
+		return
+	}
+	for i, ok := range read {
+		if !ok {
+			continue
+		}
+		r.previous[i] = map[string]int64{
+			hrtTicks:     current[hrtTicks],
+			hrtFrequency: current[hrtFrequency],
+		}
+		r.previousTime[i] = now
+	}
+}
+
+// mergeLongs returns a copy of previous which is updated with current.
+func mergeLongs(previous, current map[string]int64) map[string]int64 {
+	result := make(map[string]int64, len(previous)+len(current))
+	for name, value := range previous {
+		result[name] = value
+	}
+	for name, value := range current {
+		result[name] = value
+	}
+	return result
+}
+
+// mergeStrings returns a copy of previous which is updated with current.
+func mergeStrings(previous, current map[string]string) map[string]string {
+	result := make(map[string]string, len(previous)+len(current))
+	for name, value := range previous {
+		result[name] = value
+	}
+	for name, value := range current {
+		result[name] = value
+	}
+	return result
+}
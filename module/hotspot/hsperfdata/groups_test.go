@@ -0,0 +1,140 @@
+package hsperfdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func newTestGroups() *counterGroups {
+	return newCounterGroups([]GroupRule{
+		{Name: "cold", Prefixes: []string{"sun.cls.", "sun/ci/"}, Interval: time.Minute},
+		{Name: "urlcl", Pattern: `^sun/urlClassLoader/`, Interval: 10 * time.Second},
+	})
+}
+
+func TestCounterGroup(t *testing.T) {
+	g := newTestGroups()
+
+	assertEquals(t, 0, g.group("sun/cls/time"))
+	assertEquals(t, 0, g.group("sun/ci/totalTime"))
+	assertEquals(t, 1, g.group("sun/urlClassLoader/readClassBytesTime"))
+	assertEquals(t, -1, g.group("sun/gc/collector/0/invocations"))
+	assertEquals(t, -1, g.group("sun/os/hrt/ticks"))
+}
+
+func TestNoCounterGroups(t *testing.T) {
+	if newCounterGroups(nil) != nil {
+		t.Errorf("counterGroups should be nil without rules")
+	}
+	var s *groupSchedule
+	if s.due(time.Now()) != nil {
+		t.Errorf("due should be nil without rules")
+	}
+	var g *counterGroups
+	if g.groupSelector([]bool{true}) != nil {
+		t.Errorf("selector should be nil without rules")
+	}
+}
+
+func TestGroupSchedule(t *testing.T) {
+	g := newTestGroups()
+	s := newGroupSchedule(g, time.Second)
+	now := time.Now()
+
+	due := s.due(now)
+	assertEquals(t, true, g.groupSelector(due)("sun/cls/time"))
+	s.commit("1@100", due, now)
+
+	due = s.due(now.Add(time.Second))
+	selected := g.groupSelector(due)
+	assertEquals(t, false, selected("sun/cls/time"))
+	assertEquals(t, false, selected("sun/urlClassLoader/readClassBytesTime"))
+	assertEquals(t, true, selected("sun/gc/collector/0/invocations"))
+
+	// the fetch may be a little earlier than the interval
+	due = s.due(now.Add(9*time.Second + 600*time.Millisecond))
+	selected = g.groupSelector(due)
+	assertEquals(t, false, selected("sun/cls/time"))
+	assertEquals(t, true, selected("sun/urlClassLoader/readClassBytesTime"))
+	s.commit("1@100", due, now.Add(10*time.Second))
+
+	// all counters are read when the JVM is re-created
+	due = s.due(now.Add(11 * time.Second))
+	s.commit("1@200", due, now.Add(11*time.Second))
+	assertEquals(t, true, due[0])
+	assertEquals(t, true, due[1])
+}
+
+func TestTrackerSelector(t *testing.T) {
+	config := DefaultConfig()
+	config.CounterGroups = []GroupRule{{Name: "cold", Prefixes: []string{"sun.cls.", "sun.ci."}, Interval: time.Minute}}
+	tracker := NewTracker(&config)
+	tracker.Subscribe([]string{"sun.ci."})
+
+	selected := tracker.selector([]bool{false})
+	assertEquals(t, false, selected("sun/cls/time"))
+	assertEquals(t, true, selected("sun/ci/totalTime")) // used by a subscriber
+	assertEquals(t, true, selected("sun/gc/collector/0/invocations"))
+	assertEquals(t, true, tracker.selector([]bool{true})("sun/cls/time"))
+
+	tracker.Subscribe(nil)
+	if tracker.selector([]bool{false}) != nil {
+		t.Errorf("all counters should be read if a subscriber uses all counters")
+	}
+}
+
+func TestGroupRuleValidate(t *testing.T) {
+	for _, rule := range []GroupRule{
+		{Name: "a", Interval: time.Minute},
+		{Name: "a", Pattern: "(", Interval: time.Minute},
+		{Name: "a", Prefixes: []string{"sun/cls/"}},
+	} {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("%v should be rejected", rule)
+		}
+	}
+}
+
+func TestBuildMapStrWithGroups(t *testing.T) {
+	config := DefaultConfig()
+	p := &ProcStats{pid: "1", config: &config, period: time.Second}
+	groups := newCounterGroups([]GroupRule{
+		{Name: "cold", Prefixes: []string{"sun/cls/"}, Interval: 10 * time.Second},
+	})
+	schedule := newGroupSchedule(groups, p.period)
+	p.groups = newGroupReads(groups)
+	now := time.Now()
+
+	read := func(at time.Duration, ticks, cls, gc int64) common.MapStr {
+		due := schedule.due(now.Add(at))
+		schedule.commit("1@100", due, now.Add(at))
+		selected := groups.groupSelector(due)
+		var entries []PerfDataEntry
+		for _, entry := range []PerfDataEntry{
+			longEntry("sun/os/hrt/frequency", 1000),
+			monotonicEntry("sun/os/hrt/ticks", ticks),
+			monotonicEntry("sun/cls/time", cls),
+			monotonicEntry("sun/gc/time", gc),
+		} {
+			if selected(entry.EntryName) {
+				entries = append(entries, entry)
+			}
+		}
+		snapshot := newSnapshot(entries, now.Add(at))
+		snapshot.groups = due
+		return p.buildMapStr(snapshot)
+	}
+
+	read(0, 0, 100, 10)
+	event := read(time.Second, 1000, 200, 20)
+	assertEquals(t, nil, event["sun/cls/time"])
+	assertEquals(t, 10.0, event["sun/gc/time/rate"])
+
+	event = read(10*time.Second, 10000, 300, 30)
+	assertEquals(t, int64(300), event["sun/cls/time"])
+	assertEquals(t, int64(200), event["sun/cls/time/diff"])
+	assertEquals(t, 20.0, event["sun/cls/time/rate"])
+	assertEquals(t, int64(10), event["sun/gc/time/diff"])
+}
@@ -1,13 +1,12 @@
 package hsperfdata
 
 import (
-	"os"
+	"sync"
+	"time"
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/metricbeat/mb"
-
-	"github.com/YaSuenag/hsbeat/utils/multierror"
 )
 
 const DEBUG_SELECTOR = "hsbeat"
@@ -26,18 +25,30 @@ func init() {
 // multiple fetch calls.
 type MetricSet struct {
 	mb.BaseMetricSet
-	forceCachedEntries []string
-	pid string
-	procs map[string]ProcStats // PID to ProcStats map
+	config Config
+	tracker *Tracker
+	procs map[string]*ProcStats // PID to ProcStats map
+	groups *counterGroups // nil if no counter groups are configured
+	period time.Duration
+	mu sync.Mutex // guards tracker and procs against sampling
+	sampling bool // true if sampling between fetches is running
+	fetched time.Time // time of the last fetch, which keeps sampling running
+	done chan struct{} // closed by Close to stop sampling
 }
 
 // ProcStats type holds data for a given Java process (PID)
 type ProcStats struct {
 	pid string
-	parser *HSPerfData
+	id string // JVM ID, see Snapshot.ID
 	previousData map[string]int64
-	hsPerfDataPath string
-	isFirst bool
+	previousStrings map[string]string
+	previousTime time.Time
+	lastFullSnapshot time.Time
+	fetchesSinceFullSnapshot int
+	config *Config
+	period time.Duration
+	sampling *periodStats // samples between fetches, nil if sampling is disabled
+	groups *groupReads // nil if no counter groups are configured
 }
 
 // New create a new instance of the MetricSet
@@ -45,170 +56,186 @@ type ProcStats struct {
 // configuration entries if needed.
 func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 
-	config := struct{
-		ForceCachedEntries []string `config:"force_collect"`
-		Pid string `config:"pid"`
-	}{
-		ForceCachedEntries: []string{},
-		Pid: "0",
-	}
+	config := DefaultConfig()
 
 	if err := base.Module().UnpackConfig(&config); err != nil {
 		return nil, err
 	}
 
+	period := base.Module().Config().Period
+	if config.SamplingInterval >= period {
+		logp.Warn("sampling.interval (%v) is not shorter than period (%v), sampling between fetches is disabled", config.SamplingInterval, period)
+		config.SamplingInterval = 0
+	}
+
 	return &MetricSet{
 		BaseMetricSet: base,
-		pid: config.Pid,
-		forceCachedEntries: config.ForceCachedEntries,
-		procs: make(map[string]ProcStats, 0),
+		config: config,
+		period: period,
+		tracker: SharedTracker(&config, period),
+		procs: make(map[string]*ProcStats, 0),
+		groups: newCounterGroups(config.CounterGroups),
+		done: make(chan struct{}),
 	}, nil
 }
 
-func (m *MetricSet) attachJavaProc(pid string) error {
-
-	if _, exists := m.procs[pid]; exists {
-		return nil // pid already attached
-	}
-
-	logp.Debug(DEBUG_SELECTOR, "Attaching java process: %v", pid)
+// Close stops sampling between fetches. Metricbeat does not call it, so
+// sampling also stops when the MetricSet is not fetched (see
+// samplingIdlePeriods).
+func (m *MetricSet) Close() error {
+	close(m.done)
+	return nil
+}
 
-	inst := &HSPerfData{}
-	inst.ForceCachedEntryName = make(map[string]int)
-	for _, entry := range m.forceCachedEntries {
-		inst.ForceCachedEntryName[entry] = 1
+// procStats returns ProcStats of the JVM. ProcStats of processes which
+// have been detached are removed, and ProcStats of the JVM which is
+// re-created with the same pid is created again.
+func (m *MetricSet) procStats(pid, id string) *ProcStats {
+	p, exists := m.procs[pid]
+	if !exists || p.id != id {
+		p = &ProcStats{
+			pid: pid,
+			id: id,
+			config: &m.config,
+			period: m.period,
+		}
+		if m.config.SamplingInterval > 0 {
+			p.sampling = newPeriodStats()
+		}
+		p.groups = newGroupReads(m.groups)
+		m.procs[pid] = p
 	}
+	return p
+}
 
-	perfDataPath, err := GetHSPerfDataPath(pid)
-	if err != nil {
-		return err
+func (m *MetricSet) removeDetached() {
+	procs := m.tracker.Processes()
+	for pid := range m.procs {
+		if _, exists := procs[pid]; !exists {
+			delete(m.procs, pid)
+		}
 	}
+}
 
-	prevData := make(map[string]int64)
+func (p *ProcStats) buildMapStr(snapshot *Snapshot) common.MapStr {
+	event := common.MapStr{"pid": p.pid}
 
-	procStats := ProcStats{
-		pid: pid,
-		parser: inst,
-		previousData: prevData,
-		hsPerfDataPath: perfDataPath,
-		isFirst: true,
+	now := snapshot.Time
+	current := snapshot.Longs
+	entries := snapshot.Entries
+	if p.sampling != nil {
+		p.sampling.add(p.groups.ungroupedEntries(entries))
 	}
 
-	m.procs[pid] = procStats
-
-	return nil
-}
-
-func (m *MetricSet) detachJavaProc(pid string) {
-	logp.Debug(DEBUG_SELECTOR, "Detaching java process: %v", pid)
-	delete(m.procs, pid)
-}
+	elapsed := p.elapsed(current, now)
+	if elapsed.Seconds > 0 {
+		event["interval"] = elapsed.ToMapStr()
+	}
 
-// if configured pid equals 0 look for all running java processes
-// else, only fetch for the configured pid
-// the method updates MetricSet.procs map
-func (m *MetricSet) findAndAttachJavaProcs() error {
-	if m.pid != "0" {
-		logp.Debug(DEBUG_SELECTOR, "Fetching data for only one pid: %v", m.pid)
-		if err := m.attachJavaProc(m.pid); err != nil {
-			return err
-		}
-	} else { // need to look for Java Processes
-		logp.Debug(DEBUG_SELECTOR, "Fetching data for multiple java processes")
-		runningPids, err := GetHSPerfPids()
-		if err != nil {
-			return err
-		}
-		logp.Debug(DEBUG_SELECTOR, "Found %v running java processes", len(runningPids))
-		for _, pid := range runningPids {
-			if err := m.attachJavaProc(pid); err != nil {
-				logp.Err("Could not attach java process with pid: %v", pid, err)
-				// continue with other processes
-			}
-		}
-		// detach any proc that is no longer running
-		for attachedPid, _ := range m.procs {
-			found := false
-			for _, runningPid := range runningPids {
-				if runningPid == attachedPid {
-					found = true
-					break
-				}
-			}
-			if !found {
-				m.detachJavaProc(attachedPid)
-			}
+	full := p.isFullSnapshot(now)
+	if p.config.ChangesOnly {
+		if full {
+			event["snapshot"] = "full"
+		} else {
+			event["snapshot"] = "changes"
 		}
 	}
-	return nil
-}
 
-func (p *ProcStats) buildMapStr(entries []PerfDataEntry) common.MapStr {
-	event := common.MapStr{"pid": p.pid}
+	stringValues := snapshot.Strings
 
+	metricTypes := common.MapStr{}
+	resets := NewResets(p.config.CounterReset)
 	for _, entry := range entries {
 		if entry.DataType == 'J' {
-			event[entry.EntryName] = entry.LongValue
 			prev, exists := p.previousData[entry.EntryName]
+			if !full && exists && prev == entry.LongValue {
+				p.sampling.addSummary(event, entry.EntryName, false)
+				continue
+			}
+
+			event[entry.EntryName] = entry.LongValue
+			metricTypes[entry.EntryName] = MetricType(&entry)
+			p.sampling.addSummary(event, entry.EntryName, true)
 
 			if exists {
-				event[entry.EntryName + "/diff"] = entry.LongValue - prev
-			}
+				monotonic := entry.DataVariability == VariabilityMonotonic
+				diff := entry.LongValue - prev
+				reset := false
+				if monotonic {
+					diff, reset = CounterDelta(entry.LongValue, prev)
+				}
+				if delta, ok := p.sampling.delta(entry.EntryName); ok {
+					diff = delta // exact delta from samples between fetches
+					reset = reset || p.sampling.isReset(entry.EntryName)
+				}
+				metricTypes[entry.EntryName + "/diff"] = MetricTypeGauge
 
-			p.previousData[entry.EntryName] = entry.LongValue
+				if reset && resets.Add(entry.EntryName) {
+					event[entry.EntryName + "/diff"] = nil
+					event[entry.EntryName + "/rate"] = nil
+					continue
+				}
+				event[entry.EntryName + "/diff"] = diff
+
+				if monotonic {
+					counterElapsed := elapsed
+					if e, ok := p.groups.elapsed(entry.EntryName, current, now); ok {
+						counterElapsed = e // since the previous read of the group
+					}
+					if rate, ok := counterElapsed.Rate(prev + diff, prev); ok {
+						event[entry.EntryName + "/rate"] = rate
+						metricTypes[entry.EntryName + "/rate"] = MetricTypeGauge
+					}
+				}
+			}
 		} else {
+			prev, exists := p.previousStrings[entry.EntryName]
+			if !full && exists && prev == entry.StringValue {
+				continue
+			}
+
 			event[entry.EntryName] = entry.StringValue
 		}
 	}
 
-	return event
-}
+	resets.AddTo(event)
 
-func (p *ProcStats) publishAll() (common.MapStr, error) {
-	f, err := os.Open(p.hsPerfDataPath)
-	if err != nil {
-		if os.IsPermission(err) {
-			logp.Debug(DEBUG_SELECTOR, "Could not open %v due to perimissions error, if you want to collect data from all users hsbeat needs to run as root (%v)", p.hsPerfDataPath, err)
-		} else {
-			logp.Debug(DEBUG_SELECTOR, "Could not open %v (%v)", p.hsPerfDataPath, err)
+	if p.config.MetricTypes {
+		event["metric_type"] = metricTypes
+	}
+
+	if p.config.DerivedMetrics {
+		s := sample{
+			current: current,
+			previous: p.previousData,
+			seconds: elapsed.Seconds,
 		}
-		return nil, err
+		event["derived"] = s.computeDerived()
 	}
-	defer f.Close()
 
-	err = p.parser.ReadPrologue(f)
-	if err != nil {
-		return nil, err
+	if p.config.Normalize {
+		event["normalized"] = normalize(current, stringValues)
 	}
 
-	f.Seek(int64(p.parser.Prologue.EntryOffset), os.SEEK_SET)
-	result, err := p.parser.ReadAllEntry(f)
-	if err != nil {
-		return nil, err
+	if p.sampling != nil {
+		event["sampling"] = p.sampling.toMapStr(p.config.SamplingInterval)
+		p.sampling.reset()
 	}
 
-	return p.buildMapStr(result), nil
-}
+	snapshot.AddLabels(event)
 
-func (p *ProcStats) publishCached() (common.MapStr, error) {
-	f, err := os.Open(p.hsPerfDataPath)
-	if err != nil {
-		if os.IsPermission(err) {
-			logp.Debug(DEBUG_SELECTOR, "Could not open %v due to perimissions error, if you want to collect data from all users hsbeat needs to run as root (%v)", p.hsPerfDataPath, err)
-		} else {
-			logp.Debug(DEBUG_SELECTOR, "Could not open %v (%v)", p.hsPerfDataPath, err)
-		}
-		return nil, err
+	if p.groups != nil {
+		// keep values of groups which are not read at this fetch
+		p.groups.commit(snapshot.groups, current, now)
+		current = mergeLongs(p.previousData, current)
+		stringValues = mergeStrings(p.previousStrings, stringValues)
 	}
-	defer f.Close()
 
-	f.Seek(int64(p.parser.Prologue.EntryOffset), os.SEEK_SET)
-	result, err := p.parser.ReadCachedEntry(f)
-	if err != nil {
-		return nil, err
-	}
+	p.previousData = current
+	p.previousStrings = stringValues
+	p.previousTime = now
 
-	return p.buildMapStr(result), nil
+	return event
 }
 
 // Fetch methods implements the data gathering and data conversion to the right format
@@ -217,29 +244,31 @@ func (p *ProcStats) publishCached() (common.MapStr, error) {
 // Map of java processes is updated at each fetch interval
 // Errors are accumlated and returned only if no events were collected, otherwise events are returned and errors are just logged
 func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	errors := new(multierror.MultiError)
-
-	if err := m.findAndAttachJavaProcs(); err != nil {
-		errors.Append(err) // accumulate errors
+	m.fetched = time.Now()
+	if m.config.SamplingInterval > 0 && !m.sampling {
+		m.startSampling()
 	}
 
-	events := make([]common.MapStr, 0, len(m.procs))
-	for _, p := range m.procs {
-		var event common.MapStr
-		var err error
-		if p.isFirst {
-			event, err = p.publishAll()
-			p.isFirst = false
-		} else {
-			event, err = p.publishCached()
-		}
+	snapshots, errors := m.tracker.Update()
+	m.removeDetached()
 
-		if err != nil {
-			errors.Append(err) // accumulate errors
-		} else {
-			events = append(events, event)
+	events := make([]common.MapStr, 0, len(snapshots))
+	for pid, snapshot := range snapshots {
+		p := m.procStats(pid, snapshot.ID)
+		if p.previousData == nil {
+			// constants are shipped at the first fetch even if the process
+			// has been read by another metricset
+			snapshot = snapshot.withConstants()
 		}
+		// counters of groups which are not due may be read for other
+		// metricsets, but they are not shipped
+		snapshot = snapshot.selectEntries(m.groups.groupSelector(snapshot.groups))
+		constantsShipped := m.restore(p, snapshot)
+		events = append(events, p.buildMapStr(snapshot))
+		m.save(snapshot, constantsShipped)
 	}
 
 	if errors.HasErrors() {
@@ -0,0 +1,290 @@
+// Package hsperftest writes hsperfdata files and builds snapshots for tests of
+// the hotspot module, and has assertions which are shared by the tests.
+package hsperftest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// Units of counters in the hsperfdata file (PerfData::Units in HotSpot).
+var units = map[string]int8{
+	hsperfdata.UnitsNone:   1,
+	hsperfdata.UnitsBytes:  2,
+	hsperfdata.UnitsTicks:  3,
+	hsperfdata.UnitsEvents: 4,
+	hsperfdata.UnitsString: 5,
+	hsperfdata.UnitsHertz:  6,
+}
+
+const (
+	prologueSize = 32
+	entrySize    = 20        // size of the entry header
+	fileSize     = 32 * 1024 // PerfDataMemorySize
+)
+
+// Counter is a counter in the hsperfdata file. Units and Variability are
+// taken from the catalog of well-known counters if they are zero. Unknown
+// long counters are variable and unknown string counters are constant.
+type Counter struct {
+	Name        string      // separated by '.' as HotSpot
+	Value       interface{} // int64 or string
+	Units       int8
+	Variability int8
+}
+
+func (c *Counter) metadata() (int8, int8) {
+	u, v := c.Units, c.Variability
+	if known, ok := hsperfdata.LookupCounter(c.Name); ok {
+		if u == 0 {
+			u = units[known.Units]
+		}
+		if v == 0 {
+			v = known.Variability
+		}
+	}
+	if _, ok := c.Value.(string); ok {
+		if u == 0 {
+			u = units[hsperfdata.UnitsString]
+		}
+		if v == 0 {
+			v = hsperfdata.VariabilityConstant
+		}
+	} else {
+		if u == 0 {
+			u = units[hsperfdata.UnitsNone]
+		}
+		if v == 0 {
+			v = hsperfdata.VariabilityVariable
+		}
+	}
+	return u, v
+}
+
+func align(n, to int) int {
+	return (n + to - 1) / to * to
+}
+
+// Encode returns the hsperfdata file which has the counters in the
+// little-endian byte order, as HotSpot writes it on x86.
+func Encode(counters []Counter, modTimeStamp int64) []byte {
+	order := binary.LittleEndian
+	var entries bytes.Buffer
+	for _, c := range counters {
+		name := strings.Replace(c.Name, "/", ".", -1)
+		u, v := c.metadata()
+
+		var dataType byte = 'J'
+		var vectorLength int
+		data := make([]byte, 8)
+		if s, ok := c.Value.(string); ok {
+			dataType = 'B'
+			vectorLength = len(s) + 1
+			data = append([]byte(s), 0)
+		} else {
+			order.PutUint64(data, uint64(c.Value.(int64)))
+		}
+
+		dataOffset := entrySize + len(name) + 1
+		if dataType == 'J' {
+			dataOffset = align(dataOffset, 8)
+		}
+		length := align(dataOffset+len(data), 8)
+
+		entry := make([]byte, length)
+		order.PutUint32(entry[0:], uint32(length))
+		order.PutUint32(entry[4:], entrySize)
+		order.PutUint32(entry[8:], uint32(vectorLength))
+		entry[12] = dataType
+		entry[14] = byte(u)
+		entry[15] = byte(v)
+		order.PutUint32(entry[16:], uint32(dataOffset))
+		copy(entry[entrySize:], name)
+		copy(entry[dataOffset:], data)
+		entries.Write(entry)
+	}
+
+	used := prologueSize + entries.Len()
+	size := fileSize
+	if used > size {
+		size = align(used, 4096)
+	}
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint32(buf[0:], 0xcafec0c0)
+	buf[4] = 1 // little endian
+	buf[5] = 2 // major version
+	buf[6] = 0 // minor version
+	buf[7] = 1 // accessible
+	order.PutUint32(buf[8:], uint32(used))
+	order.PutUint64(buf[16:], uint64(modTimeStamp))
+	order.PutUint32(buf[24:], prologueSize)
+	order.PutUint32(buf[28:], uint32(len(counters)))
+	copy(buf[prologueSize:], entries.Bytes())
+	return buf
+}
+
+// dirs are temporary directories of tests which are set to TMPDIR.
+var dirs = make(map[*testing.T]string)
+
+// tempDir returns the temporary directory of the test, and sets TMPDIR to it
+// so the hotspot module finds hsperfdata files in it.
+func tempDir(t *testing.T) string {
+	if dir, ok := dirs[t]; ok {
+		return dir
+	}
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	dirs[t] = dir
+	t.Cleanup(func() { delete(dirs, t) })
+	return dir
+}
+
+// WriteFile writes the hsperfdata file of the pid to the temporary directory
+// of the test, and sets TMPDIR to it so the hotspot module finds the file. It
+// returns the path of the file. The file can be written again to update
+// values.
+func WriteFile(t *testing.T, pid string, counters []Counter, modTimeStamp int64) string {
+	dir := filepath.Join(tempDir(t), "hsperfdata_hsperftest")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, pid)
+	if err := ioutil.WriteFile(path, Encode(counters, modTimeStamp), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pid is the pid of hsperfdata files which are read by Read.
+const pid = "4242"
+
+// ReadListing reads counters from the file in the testdata directory of this
+// package. The file lists counters in the format of
+// `jcmd <pid> PerfCounter.print`, i.e. `name=value` with quoted strings.
+// Lines without '=' and lines which start with '#' are ignored.
+func ReadListing(t *testing.T, name string) []Counter {
+	_, source, _, _ := runtime.Caller(0)
+	f, err := os.Open(filepath.Join(filepath.Dir(source), "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var counters []Counter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.Index(line, "=")
+		if i < 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value := line[:i], line[i+1:]
+		if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") && len(value) >= 2 {
+			counters = append(counters, Counter{Name: name, Value: value[1 : len(value)-1]})
+			continue
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid value of %v in %v: %v", name, f.Name(), value)
+		}
+		counters = append(counters, Counter{Name: name, Value: v})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return counters
+}
+
+// Read writes the hsperfdata file of the counters, and reads it by a Process
+// as the hotspot module reads it at the first read.
+func Read(t *testing.T, counters []Counter) *hsperfdata.Snapshot {
+	WriteFile(t, pid, counters, 1)
+	proc, err := hsperfdata.NewProcess(pid, &hsperfdata.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := proc.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+// Set returns a copy of counters in which the value of the counter is
+// replaced. The counter is added if it does not exist.
+func Set(counters []Counter, name string, value interface{}) []Counter {
+	result := make([]Counter, len(counters), len(counters)+1)
+	copy(result, counters)
+	for i := range result {
+		if result[i].Name == name {
+			result[i].Value = value
+			return result
+		}
+	}
+	return append(result, Counter{Name: name, Value: value})
+}
+
+// Frequency is sun.os.hrt.frequency of snapshots which are built by
+// NewSnapshot, so a tick is a millisecond.
+const Frequency = 1000
+
+// Values are values of counters keyed by names separated by '/'. Values of
+// long counters are int64 or int, and values of string counters are string.
+type Values map[string]interface{}
+
+// NewSnapshot builds the snapshot which is read at now as the shared Tracker
+// hands it out to metricsets. sun.os.hrt.frequency is Frequency and
+// sun.os.hrt.ticks is ticks.
+func NewSnapshot(now time.Time, ticks int64, values Values) *hsperfdata.Snapshot {
+	s := &hsperfdata.Snapshot{
+		Time: now,
+		Longs: map[string]int64{
+			"sun/os/hrt/frequency": Frequency,
+			"sun/os/hrt/ticks":     ticks,
+		},
+		Strings: make(map[string]string),
+	}
+	for name, value := range values {
+		switch v := value.(type) {
+		case string:
+			s.Strings[name] = v
+		case int:
+			s.Longs[name] = int64(v)
+		default:
+			s.Longs[name] = v.(int64)
+		}
+	}
+	return s
+}
+
+// GetValue returns the value of the key in the event. The test fails if the
+// key does not exist.
+func GetValue(t *testing.T, event common.MapStr, key string) interface{} {
+	t.Helper()
+	v, err := event.GetValue(key)
+	if err != nil {
+		t.Fatalf("could not get %v: %v", key, err)
+	}
+	return v
+}
+
+// AssertEquals reports an error if actual is not equal to expected.
+func AssertEquals(t *testing.T, expected interface{}, actual interface{}) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("%v is not equal to %v", expected, actual)
+	}
+}
@@ -0,0 +1,152 @@
+package hsperfdata
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Sources of label values.
+const (
+	LabelFromCommand  = "command"
+	LabelFromJar      = "jar"
+	LabelFromProperty = "property"
+)
+
+// LabelRule extracts a label from constant counters of a JVM.
+type LabelRule struct {
+	// Name is the label name, e.g. service.name.
+	Name string `config:"name" validate:"required"`
+
+	// From is the source of the value: command (sun.rt.javaCommand), jar
+	// (the file name of the jar in sun.rt.javaCommand) or property (the
+	// system property in Property).
+	From     string `config:"from" validate:"required"`
+	Property string `config:"property"`
+
+	// Pattern is an optional regular expression applied to the value. The
+	// first capturing group (or the whole match if it has no groups) is the
+	// label value. The label is not added if the value does not match.
+	Pattern string `config:"pattern"`
+}
+
+// Validate checks the source and the pattern of the rule.
+func (r *LabelRule) Validate() error {
+	switch r.From {
+	case LabelFromCommand, LabelFromJar:
+	case LabelFromProperty:
+		if r.Property == "" {
+			return fmt.Errorf("label %v: property is required for from: %v", r.Name, r.From)
+		}
+	default:
+		return fmt.Errorf("label %v: unknown source %v", r.Name, r.From)
+	}
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("label %v: invalid pattern %v: %v", r.Name, r.Pattern, err)
+	}
+	return nil
+}
+
+type labelRule struct {
+	LabelRule
+	pattern *regexp.Regexp
+}
+
+// Labeler extracts labels of a JVM with label rules. If more than one rule
+// has the same name, the first rule which extracts a value wins.
+type Labeler struct {
+	rules []labelRule
+}
+
+// NewLabeler creates a Labeler for the rules. The rules must be validated in
+// advance.
+func NewLabeler(rules []LabelRule) *Labeler {
+	l := &Labeler{}
+	for _, rule := range rules {
+		r := labelRule{LabelRule: rule}
+		if rule.Pattern != "" {
+			r.pattern = regexp.MustCompile(rule.Pattern)
+		}
+		l.rules = append(l.rules, r)
+	}
+	return l
+}
+
+// Labels extracts labels from constant string counters. It returns nil if
+// no label is extracted or l is nil.
+func (l *Labeler) Labels(strs map[string]string) common.MapStr {
+	if l == nil || len(l.rules) == 0 {
+		return nil
+	}
+
+	var properties map[string]string
+	labels := common.MapStr{}
+	for _, rule := range l.rules {
+		if _, exists := labels[rule.Name]; exists {
+			continue
+		}
+
+		var value string
+		var ok bool
+		switch rule.From {
+		case LabelFromCommand:
+			value, ok = strs["sun/rt/javaCommand"]
+		case LabelFromJar:
+			value, ok = JarName(strs["sun/rt/javaCommand"])
+		case LabelFromProperty:
+			if properties == nil {
+				properties = ParseVMArgs("", strs["java/rt/vmArgs"]).Properties
+			}
+			value, ok = properties[rule.Property]
+			if !ok {
+				value, ok = strs["java/property/"+strings.Replace(rule.Property, ".", "/", -1)]
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if rule.pattern != nil {
+			match := rule.pattern.FindStringSubmatch(value)
+			switch {
+			case match == nil:
+				continue
+			case len(match) > 1:
+				value = match[1]
+			default:
+				value = match[0]
+			}
+		}
+		if value != "" {
+			labels[rule.Name] = value
+		}
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// JarName returns the file name of the jar from sun.rt.javaCommand, which
+// starts with the path of the jar if the JVM is launched with -jar.
+func JarName(command string) (string, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || !strings.HasSuffix(fields[0], ".jar") {
+		return "", false
+	}
+	return filepath.Base(fields[0]), true
+}
+
+// AddLabels adds labels of the JVM to events. Each event has its own copy.
+func (s *Snapshot) AddLabels(events ...common.MapStr) {
+	if len(s.Labels) == 0 {
+		return
+	}
+	for _, event := range events {
+		event["labels"] = s.Labels.Clone()
+	}
+}
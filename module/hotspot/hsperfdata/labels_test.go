@@ -0,0 +1,75 @@
+package hsperfdata
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestJarName(t *testing.T) {
+	name, ok := JarName("/opt/app/checkout-1.2.3.jar --port 8080")
+	assertEquals(t, true, ok)
+	assertEquals(t, "checkout-1.2.3.jar", name)
+
+	_, ok = JarName("org.example.Main --port 8080")
+	assertEquals(t, false, ok)
+}
+
+func TestLabels(t *testing.T) {
+	l := NewLabeler([]LabelRule{
+		{Name: "service.name", From: LabelFromProperty, Property: "app.name"},
+		{Name: "service.name", From: LabelFromJar, Pattern: `^(.+?)-\d`},
+		{Name: "service.version", From: LabelFromJar, Pattern: `-(\d[\w.]*)\.jar$`},
+		{Name: "environment", From: LabelFromCommand, Pattern: `--env=(\w+)`},
+		{Name: "os", From: LabelFromProperty, Property: "os.name"},
+	})
+
+	labels := l.Labels(map[string]string{
+		"sun/rt/javaCommand":         "/opt/app/checkout-1.2.3.jar --env=prod",
+		"java/rt/vmArgs":             "-Xmx1g",
+		"java/property/os/name":      "Linux",
+		"java/property/java/version": "11.0.2",
+	})
+	assertEquals(t, "checkout", labels["service.name"])
+	assertEquals(t, "1.2.3", labels["service.version"])
+	assertEquals(t, "prod", labels["environment"])
+	assertEquals(t, "Linux", labels["os"])
+
+	labels = l.Labels(map[string]string{
+		"sun/rt/javaCommand": "/opt/app/checkout-1.2.3.jar",
+		"java/rt/vmArgs":     "-Dapp.name=payment -Xmx1g",
+	})
+	assertEquals(t, "payment", labels["service.name"])
+	if _, exists := labels["environment"]; exists {
+		t.Errorf("environment should not be added if the pattern does not match")
+	}
+}
+
+func TestNoLabels(t *testing.T) {
+	labels := NewLabeler(nil).Labels(map[string]string{"sun/rt/javaCommand": "org.example.Main"})
+	if labels != nil {
+		t.Errorf("labels should be nil without rules")
+	}
+}
+
+func TestLabelRuleValidate(t *testing.T) {
+	for _, rule := range []LabelRule{
+		{Name: "service.name", From: "unknown"},
+		{Name: "service.name", From: LabelFromProperty},
+		{Name: "service.name", From: LabelFromCommand, Pattern: "("},
+	} {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("%v should be rejected", rule)
+		}
+	}
+}
+
+func TestAddLabels(t *testing.T) {
+	s := &Snapshot{Labels: common.MapStr{"service.name": "checkout"}}
+	first, second := common.MapStr{}, common.MapStr{}
+	s.AddLabels(first, second)
+
+	assertEquals(t, "checkout", first["labels"].(common.MapStr)["service.name"])
+	second["labels"].(common.MapStr)["service.name"] = "changed"
+	assertEquals(t, "checkout", first["labels"].(common.MapStr)["service.name"])
+}
@@ -0,0 +1,22 @@
+package hsperfdata
+
+// Metric types of long counters. They follow the semantics of time series
+// databases: a counter only goes up and should be aggregated with its rate,
+// a gauge is a value at a point of time.
+const (
+	MetricTypeCounter = "counter"
+	MetricTypeGauge   = "gauge"
+)
+
+// MetricType returns the metric type of the entry. Monotonic counters are
+// counters, variable and constant ones are gauges. It returns an empty string
+// for string counters because they are not metrics.
+func MetricType(entry *PerfDataEntry) string {
+	if entry.DataType != 'J' {
+		return ""
+	}
+	if entry.DataVariability == VariabilityMonotonic {
+		return MetricTypeCounter
+	}
+	return MetricTypeGauge
+}
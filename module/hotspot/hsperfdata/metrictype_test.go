@@ -0,0 +1,38 @@
+package hsperfdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestMetricType(t *testing.T) {
+	entry := longEntry("a", 1)
+	assertEquals(t, MetricTypeGauge, MetricType(&entry))
+
+	entry.DataVariability = VariabilityMonotonic
+	assertEquals(t, MetricTypeCounter, MetricType(&entry))
+
+	entry = stringEntry("b", "x")
+	assertEquals(t, "", MetricType(&entry))
+}
+
+func TestMetricTypeAnnotation(t *testing.T) {
+	config := DefaultConfig()
+	config.MetricTypes = true
+	p := &ProcStats{pid: "1", config: &config}
+	now := time.Now()
+
+	counter := longEntry("a", 1)
+	counter.DataVariability = VariabilityMonotonic
+	p.buildMapStr(newSnapshot([]PerfDataEntry{counter, longEntry("b", 1)}, now))
+
+	counter.LongValue = 2
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{counter, longEntry("b", 1)}, now.Add(time.Second)))
+	types := event["metric_type"].(common.MapStr)
+	assertEquals(t, MetricTypeCounter, types["a"])
+	assertEquals(t, MetricTypeGauge, types["a/diff"])
+	assertEquals(t, MetricTypeGauge, types["a/rate"])
+	assertEquals(t, MetricTypeGauge, types["b"])
+}
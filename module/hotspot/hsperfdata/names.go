@@ -0,0 +1,264 @@
+package hsperfdata
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// nameCheck is a counter name or pattern in the config which is checked
+// against the catalog and counters of attached JVMs.
+type nameCheck struct {
+	option  string // config option, e.g. force_collect
+	name    string // name as written in the config
+	prefix  bool   // the name is a prefix of counter names
+	matcher counterMatcher
+}
+
+// NameValidator warns about counter names and patterns in force_collect and
+// counters.include/exclude which match no counter. Names which match the
+// catalog are accepted at New. Others are checked against the counters of
+// the first JVM which is attached, because the catalog does not cover all
+// counters of all JDK versions.
+type NameValidator struct {
+	mu      sync.Mutex
+	pending []nameCheck
+}
+
+var (
+	warnedNamesMu sync.Mutex
+	warnedNames   = make(map[string]bool) // names are warned only once even if several metricsets check them
+)
+
+// catalogNames are names of catalog entries. In catalogSamples, "*" is
+// replaced with "0" and "**" with "x" so that patterns in the config can
+// match them.
+var catalogNames, catalogSamples []string
+
+func init() {
+	for _, c := range Catalog {
+		catalogNames = append(catalogNames, c.Name)
+		elems := strings.Split(c.Name, "/")
+		for i, elem := range elems {
+			switch elem {
+			case "*":
+				elems[i] = "0"
+			case "**":
+				elems[i] = "x"
+			}
+		}
+		catalogSamples = append(catalogSamples, strings.Join(elems, "/"))
+	}
+}
+
+// NewNameValidator checks force_collect and counter filters in the config
+// against the catalog. It returns nil if all names match the catalog.
+// Patterns must be validated in advance.
+func NewNameValidator(config *Config) *NameValidator {
+	v := &NameValidator{}
+	for _, name := range config.ForceCachedEntries {
+		if _, exists := LookupCounter(name); !exists {
+			v.add(nameCheck{option: "force_collect", name: name, matcher: exactCounterName(name)})
+		}
+	}
+	for _, option := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"counters.include", config.Counters.Include},
+		{"counters.exclude", config.Counters.Exclude},
+	} {
+		for _, pattern := range option.patterns {
+			m, err := compileCounterPattern(pattern)
+			if err != nil {
+				continue // rejected by Validate
+			}
+			prefix := !strings.HasPrefix(pattern, "regexp:") && !strings.ContainsAny(pattern, "*?[")
+			if prefix && catalogHasPrefix(strings.Replace(pattern, ".", "/", -1)) {
+				continue
+			}
+			if !matchAnyName(m, catalogSamples) {
+				v.add(nameCheck{option: option.name, name: pattern, prefix: prefix, matcher: m})
+			}
+		}
+	}
+	if len(v.pending) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v *NameValidator) add(c nameCheck) {
+	v.pending = append(v.pending, c)
+}
+
+// exactCounterName matches the name in dotted or slashed form.
+func exactCounterName(name string) counterMatcher {
+	name = strings.Replace(name, ".", "/", -1)
+	return func(n string) bool {
+		return n == name
+	}
+}
+
+// catalogHasPrefix returns true if a name which matches a catalog entry can
+// start with the prefix. "*" in catalog entries matches any element.
+func catalogHasPrefix(prefix string) bool {
+	elems := strings.Split(prefix, "/")
+	for _, c := range Catalog {
+		if hasElementPrefix(strings.Split(c.Name, "/"), elems) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasElementPrefix(pattern, elems []string) bool {
+	for i, elem := range elems {
+		if i >= len(pattern) {
+			return false
+		}
+		switch {
+		case pattern[i] == "**":
+			return true
+		case pattern[i] == "*":
+			continue
+		case i == len(elems)-1:
+			return strings.HasPrefix(pattern[i], elem) // the last element can be partial
+		case pattern[i] != elem:
+			return false
+		}
+	}
+	return true
+}
+
+func matchAnyName(m counterMatcher, names []string) bool {
+	for _, name := range names {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check checks pending names against the entries of a JVM which are read at
+// the first read, and warns about names which match neither the catalog nor
+// the entries. Each name is checked only once.
+func (v *NameValidator) Check(entries []PerfDataEntry) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.pending) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.EntryName)
+	}
+	for _, c := range v.pending {
+		if !matchAnyName(c.matcher, names) {
+			warnUnknownName(c, suggestNames(c, names))
+		}
+	}
+	v.pending = nil
+}
+
+func warnUnknownName(c nameCheck, suggestions []string) {
+	warnedNamesMu.Lock()
+	defer warnedNamesMu.Unlock()
+	key := c.option + "\x00" + c.name
+	if warnedNames[key] {
+		return
+	}
+	warnedNames[key] = true
+
+	if len(suggestions) == 0 {
+		logp.Warn("%v: %v matches no counter", c.option, c.name)
+	} else {
+		logp.Warn("%v: %v matches no counter, did you mean %v?", c.option, c.name, strings.Join(suggestions, ", "))
+	}
+}
+
+// maxSuggestions is the max number of suggestions for an unknown name.
+const maxSuggestions = 3
+
+// suggestNames returns counter names of the catalog and the JVM which are
+// close to the name in the config. If the name is a prefix, prefixes of
+// counter names are suggested. Names are compared in slashed form, and no
+// names are suggested for regular expressions.
+func suggestNames(c nameCheck, seen []string) []string {
+	if strings.HasPrefix(c.name, "regexp:") {
+		return nil
+	}
+	name := strings.Replace(c.name, ".", "/", -1)
+	maxDistance := len(name) / 4
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	distances := make(map[string]int)
+	for _, names := range [][]string{catalogNames, seen} {
+		for _, candidate := range names {
+			if c.prefix && len(candidate) > len(name) {
+				candidate = candidate[:len(name)]
+			}
+			if _, exists := distances[candidate]; exists {
+				continue
+			}
+			if d := levenshtein(name, candidate); d <= maxDistance {
+				distances[candidate] = d
+			}
+		}
+	}
+
+	suggestions := make([]string, 0, len(distances))
+	for candidate := range distances {
+		suggestions = append(suggestions, candidate)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		di, dj := distances[suggestions[i]], distances[suggestions[j]]
+		if di != dj {
+			return di < dj
+		}
+		return suggestions[i] < suggestions[j]
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,53 @@
+package hsperfdata
+
+import (
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	assertEquals(t, 0, levenshtein("abc", "abc"))
+	assertEquals(t, 3, levenshtein("", "abc"))
+	assertEquals(t, 1, levenshtein("colector", "collector"))
+	assertEquals(t, 3, levenshtein("kitten", "sitting"))
+}
+
+func TestNameValidatorAcceptsCatalog(t *testing.T) {
+	config := DefaultConfig()
+	config.ForceCachedEntries = []string{"sun/os/hrt/frequency", "sun.rt.createVmBeginTime", "java.property.java.vm.name"}
+	config.Counters.Include = []string{"sun.gc.", "sun/gc/collector/*/time", "regexp:^java/threads/"}
+	config.Counters.Exclude = []string{"sun.gc.collector.1."}
+	if v := NewNameValidator(&config); v != nil {
+		t.Errorf("names in the catalog should be accepted: %v", v.pending)
+	}
+}
+
+func TestNameValidatorChecksSeenCounters(t *testing.T) {
+	config := DefaultConfig()
+	config.ForceCachedEntries = []string{"sun.os.hrt.frequencyy", "sun.zip.zipFile"}
+	config.Counters.Exclude = []string{"sun.gc.colector."}
+	v := NewNameValidator(&config)
+	if v == nil {
+		t.Fatalf("unknown names should be checked")
+	}
+	assertEquals(t, 3, len(v.pending))
+
+	v.Check([]PerfDataEntry{longEntry("sun/zip/zipFile", 1)})
+	assertEquals(t, 0, len(v.pending))
+	assertEquals(t, true, warnedNames["force_collect\x00sun.os.hrt.frequencyy"])
+	assertEquals(t, true, warnedNames["counters.exclude\x00sun.gc.colector."])
+	assertEquals(t, false, warnedNames["force_collect\x00sun.zip.zipFile"])
+}
+
+func TestSuggestNames(t *testing.T) {
+	suggestions := suggestNames(nameCheck{name: "sun.os.hrt.frequencyy"}, nil)
+	assertEquals(t, "sun/os/hrt/frequency", suggestions[0])
+
+	suggestions = suggestNames(nameCheck{name: "sun.gc.colector.", prefix: true}, nil)
+	assertEquals(t, "sun/gc/collector", suggestions[0])
+
+	suggestions = suggestNames(nameCheck{name: "sun.zip.zipFiels"}, []string{"sun/zip/zipFiles"})
+	assertEquals(t, "sun/zip/zipFiles", suggestions[0])
+
+	assertEquals(t, 0, len(suggestNames(nameCheck{name: "regexp:^sun/gc/"}, nil)))
+	assertEquals(t, 0, len(suggestNames(nameCheck{name: "com.example.counter"}, nil)))
+}
@@ -0,0 +1,251 @@
+package hsperfdata
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Kinds of garbage collectors which are detected by DetectGC.
+const (
+	GCSerial     = "serial"
+	GCParallel   = "parallel"
+	GCCMS        = "cms"
+	GCG1         = "g1"
+	GCZ          = "zgc"
+	GCShenandoah = "shenandoah"
+	GCUnknown    = "unknown"
+)
+
+// Roles of collections in the normalized schema.
+const (
+	CollectionMinor = "minor"
+	CollectionMajor = "major"
+)
+
+const (
+	gcPolicyName = "sun/gc/policy/name"
+	permGen      = 2 // PermGen is the third generation until JDK 7
+)
+
+// DetectGC detects the garbage collector from sun.gc.policy.name and names
+// of collectors (sun.gc.collector.N.name).
+func DetectGC(policy string, collectors []string) string {
+	switch {
+	case policy == "GarbageFirst":
+		return GCG1
+	case strings.HasPrefix(policy, "ParScav"):
+		return GCParallel
+	case strings.HasSuffix(policy, ":CMS"):
+		return GCCMS
+	case policy == "Copy:MSC":
+		return GCSerial
+	case strings.Contains(policy, "Shenandoah"):
+		return GCShenandoah
+	case policy == "ZGC" || policy == "Z":
+		return GCZ
+	}
+
+	for _, name := range collectors {
+		switch {
+		case strings.HasPrefix(name, "G1"):
+			return GCG1
+		case strings.HasPrefix(name, "PS"):
+			return GCParallel
+		case name == "ParNew" || name == "CMS":
+			return GCCMS
+		case name == "Copy" || name == "MSC" || strings.HasPrefix(name, "Serial"):
+			return GCSerial
+		case strings.HasPrefix(name, "Shenandoah"):
+			return GCShenandoah
+		case strings.HasPrefix(name, "Z ") || strings.HasPrefix(name, "ZGC"):
+			return GCZ
+		}
+	}
+
+	return GCUnknown
+}
+
+// IsGenerational returns true if the collector has young and old generations.
+func IsGenerational(gc string) bool {
+	return gc != GCZ && gc != GCShenandoah
+}
+
+// Collector is a collector which is published as sun.gc.collector.<Index>.
+type Collector struct {
+	Index int
+	Name  string
+}
+
+// Collectors returns collectors in index order. Indices may have gaps, e.g.
+// ZGC publishes its collector at index 2.
+func Collectors(strs map[string]string) []Collector {
+	var collectors []Collector
+	for name, value := range strs {
+		if i, ok := collectorIndex(name, "name"); ok {
+			collectors = append(collectors, Collector{Index: i, Name: value})
+		}
+	}
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].Index < collectors[j].Index
+	})
+	return collectors
+}
+
+// collectorIndex returns the index of the collector if the name is the
+// counter of a collector, i.e. sun.gc.collector.<index>.<counter>.
+func collectorIndex(name, counter string) (int, bool) {
+	const prefix = "sun/gc/collector/"
+	suffix := "/" + counter
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(name[len(prefix) : len(name)-len(suffix)])
+	return i, err == nil
+}
+
+// CollectorNames returns names of collectors in index order.
+func CollectorNames(strs map[string]string) []string {
+	collectors := Collectors(strs)
+	names := make([]string, 0, len(collectors))
+	for _, c := range collectors {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// concurrentCollectionRoles are roles of collectors of ZGC and Shenandoah by
+// sun.gc.collector.<index>.name. These collectors count pauses of concurrent
+// cycles, so their invocations are the number of pauses.
+var concurrentCollectionRoles = map[string]map[string]string{
+	GCShenandoah: {
+		"Shenandoah partial": CollectionMinor,
+		"Shenandoah full":    CollectionMajor,
+	},
+	GCZ: {
+		"Z concurrent cycle pauses":   CollectionMajor,
+		"ZGC minor collection pauses": CollectionMinor,
+		"ZGC major collection pauses": CollectionMajor,
+	},
+}
+
+// CollectionRole returns the role (minor or major) of the collector at the
+// index. Generational collectors use collector 0 for minor and collector 1
+// for major collections. ZGC and Shenandoah collect the whole heap, so their
+// concurrent cycles are regarded as major collections, except minor
+// collections of generational ZGC (and partial cycles of Shenandoah).
+func CollectionRole(gc string, index int, name string) string {
+	if IsGenerational(gc) {
+		switch index {
+		case minorCollector:
+			return CollectionMinor
+		case majorCollector:
+			return CollectionMajor
+		}
+		return ""
+	}
+	return concurrentCollectionRoles[gc][name]
+}
+
+// pool builds used, committed, max and initial capacity of a memory pool from
+// counters which have the prefix.
+func pool(longs map[string]int64, prefix string, used int64) common.MapStr {
+	result := common.MapStr{"used": common.MapStr{"bytes": used}}
+	if v, ok := longs[prefix+"capacity"]; ok {
+		result["committed"] = common.MapStr{"bytes": v}
+	}
+	if v, ok := longs[prefix+"maxCapacity"]; ok {
+		result["max"] = common.MapStr{"bytes": v}
+	}
+	if v, ok := longs[prefix+"minCapacity"]; ok {
+		result["init"] = common.MapStr{"bytes": v}
+	}
+	return result
+}
+
+func generationPool(longs map[string]int64, gen int) (common.MapStr, bool) {
+	used, ok := GenerationUsed(longs, gen)
+	if !ok {
+		return nil, false
+	}
+	return pool(longs, "sun/gc/generation/"+strconv.Itoa(gen)+"/", used), true
+}
+
+func namedPool(longs map[string]int64, prefix string) (common.MapStr, bool) {
+	used, ok := longs[prefix+"used"]
+	if !ok {
+		return nil, false
+	}
+	return pool(longs, prefix, used), true
+}
+
+// TicksToMillis converts ticks of the high resolution timer to milliseconds.
+// It returns false if sun.os.hrt.frequency is not available in longs.
+func TicksToMillis(ticks int64, longs map[string]int64) (float64, bool) {
+	freq := longs[hrtFrequency]
+	if freq <= 0 {
+		return 0, false
+	}
+	return float64(ticks) * 1000 / float64(freq), true
+}
+
+// normalize maps raw sun.gc counters onto the stable schema which does not
+// depend on the JDK version and the garbage collector.
+func normalize(longs map[string]int64, strs map[string]string) common.MapStr {
+	collectors := Collectors(strs)
+	gc := DetectGC(strs[gcPolicyName], CollectorNames(strs))
+
+	result := common.MapStr{
+		"gc": common.MapStr{
+			"name":   gc,
+			"policy": strs[gcPolicyName],
+		},
+	}
+
+	memory := common.MapStr{}
+	if IsGenerational(gc) {
+		if young, ok := generationPool(longs, youngGen); ok {
+			memory["young"] = young
+		}
+		if old, ok := generationPool(longs, oldGen); ok {
+			memory["old"] = old
+		}
+	} else if heap, ok := generationPool(longs, oldGen); ok {
+		memory["old"] = heap
+	}
+	if metaspace, ok := namedPool(longs, "sun/gc/metaspace/"); ok {
+		memory["metaspace"] = metaspace
+	} else if perm, ok := generationPool(longs, permGen); ok {
+		memory["metaspace"] = perm
+	}
+	if ccs, ok := namedPool(longs, "sun/gc/compressedclassspace/"); ok {
+		memory["compressed_class_space"] = ccs
+	}
+	if len(memory) > 0 {
+		result["memory"] = memory
+	}
+
+	collections := common.MapStr{}
+	for _, c := range collectors {
+		role := CollectionRole(gc, c.Index, c.Name)
+		if role == "" {
+			continue
+		}
+		count, ok := longs[CollectorCounter(c.Index, "invocations")]
+		if !ok {
+			continue
+		}
+		collection := common.MapStr{"count": count, "collector": c.Name}
+		if ms, ok := TicksToMillis(longs[CollectorCounter(c.Index, "time")], longs); ok {
+			collection["time"] = common.MapStr{"ms": ms}
+		}
+		collections[role] = collection
+	}
+	if len(collections) > 0 {
+		result["collections"] = collections
+	}
+
+	return result
+}
@@ -0,0 +1,50 @@
+package hsperfdata_test
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+// normalized reads the listing and returns normalized fields of the event of
+// the hsperfdata metricset.
+func normalized(t *testing.T, listing string) common.MapStr {
+	snapshot := hsperftest.Read(t, hsperftest.ReadListing(t, listing))
+	return hsperfdata.Normalize(snapshot.Longs, snapshot.Strings)
+}
+
+func TestNormalizeZGC(t *testing.T) {
+	n := normalized(t, "jdk17-zgc.txt")
+
+	hsperftest.AssertEquals(t, hsperfdata.GCZ, hsperftest.GetValue(t, n, "gc.name"))
+	hsperftest.AssertEquals(t, int64(121634816), hsperftest.GetValue(t, n, "memory.old.used.bytes"))
+	hsperftest.AssertEquals(t, int64(36), hsperftest.GetValue(t, n, "collections.major.count"))
+	hsperftest.AssertEquals(t, "Z concurrent cycle pauses", hsperftest.GetValue(t, n, "collections.major.collector"))
+	hsperftest.AssertEquals(t, 6.123321, hsperftest.GetValue(t, n, "collections.major.time.ms"))
+	if _, err := n.GetValue("collections.minor"); err == nil {
+		t.Errorf("ZGC should not have minor collections")
+	}
+}
+
+func TestNormalizeGenerationalZGC(t *testing.T) {
+	n := normalized(t, "jdk21-zgc-generational.txt")
+
+	hsperftest.AssertEquals(t, hsperfdata.GCZ, hsperftest.GetValue(t, n, "gc.name"))
+	hsperftest.AssertEquals(t, int64(57), hsperftest.GetValue(t, n, "collections.minor.count"))
+	hsperftest.AssertEquals(t, "ZGC minor collection pauses", hsperftest.GetValue(t, n, "collections.minor.collector"))
+	hsperftest.AssertEquals(t, int64(4), hsperftest.GetValue(t, n, "collections.major.count"))
+	hsperftest.AssertEquals(t, "ZGC major collection pauses", hsperftest.GetValue(t, n, "collections.major.collector"))
+}
+
+func TestNormalizeShenandoah(t *testing.T) {
+	n := normalized(t, "jdk17-shenandoah.txt")
+
+	hsperftest.AssertEquals(t, hsperfdata.GCShenandoah, hsperftest.GetValue(t, n, "gc.name"))
+	hsperftest.AssertEquals(t, int64(98566144), hsperftest.GetValue(t, n, "memory.old.used.bytes"))
+	hsperftest.AssertEquals(t, int64(0), hsperftest.GetValue(t, n, "collections.minor.count"))
+	hsperftest.AssertEquals(t, int64(48), hsperftest.GetValue(t, n, "collections.major.count"))
+	hsperftest.AssertEquals(t, "Shenandoah full", hsperftest.GetValue(t, n, "collections.major.collector"))
+}
@@ -0,0 +1,71 @@
+package hsperfdata
+
+import (
+	"testing"
+)
+
+func TestDetectGC(t *testing.T) {
+	assertEquals(t, GCG1, DetectGC("GarbageFirst", nil))
+	assertEquals(t, GCParallel, DetectGC("ParScav:MSC", nil))
+	assertEquals(t, GCCMS, DetectGC("ParNew:CMS", nil))
+	assertEquals(t, GCSerial, DetectGC("Copy:MSC", nil))
+	assertEquals(t, GCParallel, DetectGC("", []string{"PSScavenge", "PSParallelCompact"}))
+	assertEquals(t, GCShenandoah, DetectGC("", []string{"Shenandoah Pauses", "Shenandoah Cycles"}))
+	assertEquals(t, GCZ, DetectGC("", []string{"Z concurrent cycle pauses"}))
+	assertEquals(t, GCUnknown, DetectGC("", nil))
+}
+
+func TestNormalizeJDK8(t *testing.T) {
+	longs := baseCounters()
+	strs := map[string]string{
+		gcPolicyName:              "ParScav:MSC",
+		"sun/gc/collector/0/name": "PSScavenge",
+		"sun/gc/collector/1/name": "PSParallelCompact",
+	}
+	n := normalize(longs, strs)
+
+	assertEquals(t, GCParallel, getValue(t, n, "gc.name"))
+	assertEquals(t, int64(150), getValue(t, n, "memory.young.used.bytes"))
+	assertEquals(t, int64(600), getValue(t, n, "memory.young.max.bytes"))
+	assertEquals(t, int64(350), getValue(t, n, "memory.old.used.bytes"))
+	assertEquals(t, int64(40), getValue(t, n, "memory.metaspace.used.bytes"))
+	assertEquals(t, int64(10), getValue(t, n, "collections.minor.count"))
+	assertEquals(t, 400.0, getValue(t, n, "collections.minor.time.ms"))
+	assertEquals(t, "PSParallelCompact", getValue(t, n, "collections.major.collector"))
+}
+
+func TestNormalizePermGen(t *testing.T) {
+	longs := baseCounters()
+	delete(longs, metaspaceUsed)
+	longs["sun/gc/generation/2/space/0/used"] = 30
+	longs["sun/gc/generation/2/maxCapacity"] = 90
+	n := normalize(longs, map[string]string{gcPolicyName: "ParNew:CMS"})
+
+	assertEquals(t, GCCMS, getValue(t, n, "gc.name"))
+	assertEquals(t, int64(30), getValue(t, n, "memory.metaspace.used.bytes"))
+	assertEquals(t, int64(90), getValue(t, n, "memory.metaspace.max.bytes"))
+}
+
+func TestCollectionRoleOfConcurrentCollector(t *testing.T) {
+	assertEquals(t, CollectionMinor, CollectionRole(GCShenandoah, 0, "Shenandoah partial"))
+	assertEquals(t, CollectionMajor, CollectionRole(GCShenandoah, 1, "Shenandoah full"))
+	assertEquals(t, CollectionMinor, CollectionRole(GCG1, 0, "G1 incremental collections"))
+	assertEquals(t, CollectionMajor, CollectionRole(GCZ, 2, "Z concurrent cycle pauses"))
+	assertEquals(t, CollectionMinor, CollectionRole(GCZ, 0, "ZGC minor collection pauses"))
+	assertEquals(t, CollectionMajor, CollectionRole(GCZ, 2, "ZGC major collection pauses"))
+	assertEquals(t, "", CollectionRole(GCZ, 1, "ZGC Major Cycles")) // MXBean name
+}
+
+func TestCollectorsWithGaps(t *testing.T) {
+	collectors := Collectors(map[string]string{
+		"sun/gc/collector/2/name":  "ZGC major collection pauses",
+		"sun/gc/collector/0/name":  "ZGC minor collection pauses",
+		"sun/gc/collector/x/name":  "invalid",
+		"sun/gc/generation/0/name": "young",
+	})
+	if len(collectors) != 2 {
+		t.Fatalf("2 collectors are expected, but %v", collectors)
+	}
+	assertEquals(t, Collector{0, "ZGC minor collection pauses"}, collectors[0])
+	assertEquals(t, Collector{2, "ZGC major collection pauses"}, collectors[1])
+}
@@ -29,6 +29,13 @@ import(
 )
 
 
+// Values of PerfDataEntry.DataVariability
+const (
+  VariabilityConstant int8 = 1
+  VariabilityMonotonic int8 = 2
+  VariabilityVariable int8 = 3
+)
+
 type PerfDataPrologue struct{
   Magic uint32
   ByteOrder int8
@@ -64,8 +71,20 @@ type HSPerfData struct {
   byteOrder binary.ByteOrder
   entryCache []PerfDataEntry
   ForceCachedEntryName map[string]int
+
+  // Filter selects modifiable entries to be cached. All modifiable entries
+  // are cached if Filter is nil.
+  Filter func(name string) bool
+
+  // BytesRead is the number of bytes which are read from the file by the
+  // last ReadAllEntry or ReadSelectedEntry, excluding the prologue.
+  BytesRead int64
 }
 
+// maxReadGap is the max gap between values of selected entries which are
+// read at once by ReadSelectedEntry.
+const maxReadGap = 512
+
 // returns the path to the hsperfdata file for a given pid
 // it searches in all hsperfdata user directories (using a glob mattern)
 // pids are assumed to be unique regardless of username
@@ -116,7 +135,7 @@ func GetHSPerfPids() ([]string, error) {
   for _, filePath := range hsperfFiles {
     file, err := os.Stat(filePath) // make sure we can read the file
     if err != nil {
-      logp.Warn("Could not read hsperf file: %v, skipping it", filePath, err)
+      logp.Warn("Could not read hsperf file: %v, skipping it (%v)", filePath, err)
       continue
     }
     if !file.IsDir() { // take only files
@@ -171,7 +190,7 @@ func (this *HSPerfData) ReadPrologue(f *os.File) error {
   this.Prologue.Overflow = int32(this.byteOrder.Uint32(this.globalbuf[:4]))
 
   reader.Read(this.globalbuf[:8])
-  this.Prologue.ModTimeStamp = int64(this.byteOrder.Uint32(this.globalbuf[:8]))
+  this.Prologue.ModTimeStamp = int64(this.byteOrder.Uint64(this.globalbuf[:8]))
 
   reader.Read(this.globalbuf[:4])
   this.Prologue.EntryOffset = int32(this.byteOrder.Uint32(this.globalbuf[:4]))
@@ -236,7 +255,8 @@ func (this *HSPerfData) ReadAllEntry(f *os.File) ([]PerfDataEntry, error){
   }
 
   var buf []byte = make([]byte, fileinfo.Size() - 32)
-  f.Read(buf)
+  n, _ := f.Read(buf)
+  this.BytesRead = int64(n)
 
   var result []PerfDataEntry = make([]PerfDataEntry, this.Prologue.NumEntries)
   this.entryCache = make([]PerfDataEntry, 0, this.Prologue.NumEntries)
@@ -286,8 +306,10 @@ func (this *HSPerfData) ReadAllEntry(f *os.File) ([]PerfDataEntry, error){
 
     }
 
-    if result[i].DataVariability != 1 {  // Modifiable value
-      this.entryCache = append(this.entryCache, result[i])
+    if result[i].DataVariability != VariabilityConstant {  // Modifiable value
+      if this.Filter == nil || this.Filter(result[i].EntryName) {
+        this.entryCache = append(this.entryCache, result[i])
+      }
     } else {
       _, exists := this.ForceCachedEntryName[result[i].EntryName]
       if exists {
@@ -303,39 +325,55 @@ func (this *HSPerfData) ReadAllEntry(f *os.File) ([]PerfDataEntry, error){
 }
 
 func (this *HSPerfData) ReadCachedEntry(f *os.File) ([]PerfDataEntry, error){
-  fileinfo, err := f.Stat()
-  if err != nil {
-      return nil, err
-  }
-
-  var buf []byte = make([]byte, fileinfo.Size() - 32)
-  f.Read(buf)
-
-  var result []PerfDataEntry = make([]PerfDataEntry, len(this.entryCache))
-
-  reader := bytes.NewReader(buf)
-  for i, entry := range this.entryCache {
-    reader.Seek(entry.FileOffset, os.SEEK_CUR)
-
-    result[i] = entry
+  return this.ReadSelectedEntry(f, nil)
+}
 
-    if result[i].DataType == 'B' {
-      err := this.readEntryValueAsString(reader,
-                                            result[i].FileOffset, &result[i])
+// ReadSelectedEntry reads values of cached entries which are selected.
+// All cached entries are read if selected is nil. Only ranges of the file
+// which have values of selected entries are read.
+func (this *HSPerfData) ReadSelectedEntry(f *os.File, selected func(name string) bool) ([]PerfDataEntry, error){
+  var result []PerfDataEntry = make([]PerfDataEntry, 0, len(this.entryCache))
+  for _, entry := range this.entryCache {
+    if selected == nil || selected(entry.EntryName) {
+      result = append(result, entry)
+    }
+  }
 
-      if err != nil {
-        return nil, err
+  this.BytesRead = 0
+  base := int64(this.Prologue.EntryOffset)
+  for start := 0; start < len(result); {
+    // merge values which are close to each other into one read
+    from := result[start].FileOffset + int64(result[start].DataOffset)
+    to := result[start].FileOffset + int64(result[start].EntryLength)
+    end := start + 1
+    for ; end < len(result); end++ {
+      next := result[end].FileOffset + int64(result[end].DataOffset)
+      if next < to || next - to > maxReadGap {
+        break
       }
+      to = result[end].FileOffset + int64(result[end].EntryLength)
+    }
 
-    } else if result[i].DataType == 'J' {
-      err := this.readEntryValueAsLong(reader, result[i].FileOffset, &result[i])
+    buf := make([]byte, to - from)
+    n, err := f.ReadAt(buf, base + from)
+    this.BytesRead += int64(n)
+    if err != nil {
+      return nil, err
+    }
 
+    reader := bytes.NewReader(buf)
+    for i := start; i < end; i++ {
+      StartOfs := result[i].FileOffset - from
+      if result[i].DataType == 'B' {
+        err = this.readEntryValueAsString(reader, StartOfs, &result[i])
+      } else if result[i].DataType == 'J' {
+        err = this.readEntryValueAsLong(reader, StartOfs, &result[i])
+      }
       if err != nil {
         return nil, err
       }
-
     }
-
+    start = end
   }
 
   return result, nil
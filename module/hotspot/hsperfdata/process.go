@@ -0,0 +1,749 @@
+package hsperfdata
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/utils/multierror"
+)
+
+// Snapshot holds counter values of a Java process which are read at a time.
+type Snapshot struct {
+	// ID identifies the JVM across restarts of hsbeat (see JVMID). It is
+	// empty if the JVM cannot be identified.
+	ID string
+
+	Time time.Time
+
+	// ModTimeStamp is the time stamp in ticks when the JVM updated the
+	// hsperfdata file last time.
+	ModTimeStamp int64
+
+	// Entries are the entries which are read at this time. Constant entries
+	// are included only at the first read.
+	Entries []PerfDataEntry
+
+	// Longs and Strings hold the values of Entries and all constants which
+	// are read at the first read.
+	Longs   map[string]int64
+	Strings map[string]string
+
+	// Constants and ConstantStrings hold the values of constant entries
+	// which are read at the first read. They are shared by all snapshots of
+	// the process, so they must not be modified.
+	Constants       map[string]int64
+	ConstantStrings map[string]string
+
+	// Labels are extracted from constants by label rules. They are shared
+	// by all snapshots of the process, so use AddLabels to add them to
+	// events.
+	Labels common.MapStr
+
+	constantEntries []PerfDataEntry // constant entries which are read at the first read
+	groups          []bool          // counter groups which are read, nil if all counters are read
+}
+
+func newSnapshot(entries []PerfDataEntry, now time.Time) *Snapshot {
+	s := &Snapshot{
+		Time:    now,
+		Entries: entries,
+		Longs:   make(map[string]int64, len(entries)),
+		Strings: make(map[string]string),
+	}
+	for _, entry := range entries {
+		if entry.DataType == 'J' {
+			s.Longs[entry.EntryName] = entry.LongValue
+		} else {
+			s.Strings[entry.EntryName] = entry.StringValue
+		}
+	}
+	return s
+}
+
+// withConstants returns a copy of the snapshot whose Entries include the
+// constant entries of the process, for a metricset which gets the process
+// after its first read.
+func (s *Snapshot) withConstants() *Snapshot {
+	c := *s
+	c.Entries = make([]PerfDataEntry, 0, len(s.constantEntries)+len(s.Entries))
+	c.Entries = append(c.Entries, s.constantEntries...)
+	for _, entry := range s.Entries {
+		if entry.DataVariability != VariabilityConstant {
+			c.Entries = append(c.Entries, entry)
+		}
+	}
+	return &c
+}
+
+// selectEntries returns a copy of the snapshot which has only constants and
+// selected entries. Snapshots are shared by metricsets, so a metricset which
+// modifies the snapshot must copy it by this even if selected is nil.
+func (s *Snapshot) selectEntries(selected func(name string) bool) *Snapshot {
+	c := *s
+	if selected == nil {
+		return &c
+	}
+	c.Entries = make([]PerfDataEntry, 0, len(s.Entries))
+	c.Longs = make(map[string]int64, len(s.Longs))
+	c.Strings = make(map[string]string, len(s.Strings))
+	for name, value := range s.Constants {
+		c.Longs[name] = value
+	}
+	for name, value := range s.ConstantStrings {
+		c.Strings[name] = value
+	}
+	for _, entry := range s.Entries {
+		if entry.DataVariability != VariabilityConstant && !selected(entry.EntryName) {
+			continue
+		}
+		c.Entries = append(c.Entries, entry)
+		if entry.DataType == 'J' {
+			c.Longs[entry.EntryName] = entry.LongValue
+		} else {
+			c.Strings[entry.EntryName] = entry.StringValue
+		}
+	}
+	return &c
+}
+
+// Process reads the hsperfdata file of a Java process. All entries are read
+// at the first read, and only modifiable entries (and entries in
+// force_collect) are read after that.
+type Process struct {
+	Pid string
+
+	mu              sync.Mutex // guards reads, which may be done by a sampling goroutine
+	parser          *HSPerfData
+	redactor        *Redactor
+	labeler         *Labeler
+	filter          *CounterFilter
+	names           *NameValidator
+	store           *Store
+	id              string
+	labels          common.MapStr
+	hsPerfDataPath  string
+	isFirst         bool
+	constants       map[string]int64 // constant values which are read at the first read
+	constantStrings map[string]string
+	constantEntries []PerfDataEntry
+}
+
+// ProcessOptions are shared by all processes which are attached by a
+// Tracker. Nil fields disable each feature.
+type ProcessOptions struct {
+	// ForceCollect are constants which are read at every read. Names can be
+	// written with '.' or '/'.
+	ForceCollect []string
+
+	// Redactor redacts string counters.
+	Redactor *Redactor
+
+	// Labeler extracts labels from constants.
+	Labeler *Labeler
+
+	// Filter selects modifiable counters which are read.
+	Filter *CounterFilter
+
+	// Names checks counter names in the config against the counters which
+	// are read at the first read.
+	Names *NameValidator
+
+	// Store keeps the state of processes across restarts.
+	Store *Store
+}
+
+// NewProcess finds the hsperfdata file of the pid.
+func NewProcess(pid string, options *ProcessOptions) (*Process, error) {
+	perfDataPath, err := GetHSPerfDataPath(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	inst := &HSPerfData{}
+	inst.ForceCachedEntryName = make(map[string]int)
+	for _, entry := range options.ForceCollect {
+		inst.ForceCachedEntryName[strings.Replace(entry, ".", "/", -1)] = 1
+	}
+	if options.Filter != nil {
+		inst.Filter = options.Filter.Match
+	}
+
+	return &Process{
+		Pid:             pid,
+		parser:          inst,
+		redactor:        options.Redactor,
+		labeler:         options.Labeler,
+		filter:          options.Filter,
+		names:           options.Names,
+		store:           options.Store,
+		hsPerfDataPath:  perfDataPath,
+		isFirst:         true,
+		constants:       make(map[string]int64),
+		constantStrings: make(map[string]string),
+	}, nil
+}
+
+func (p *Process) open() (*os.File, error) {
+	f, err := os.Open(p.hsPerfDataPath)
+	if err != nil {
+		if os.IsPermission(err) {
+			statPermissionErrors.Add(1)
+			logp.Debug(DEBUG_SELECTOR, "Could not open %v due to perimissions error, if you want to collect data from all users hsbeat needs to run as root (%v)", p.hsPerfDataPath, err)
+		} else {
+			logp.Debug(DEBUG_SELECTOR, "Could not open %v (%v)", p.hsPerfDataPath, err)
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// prologueSize is the size of the prologue of the hsperfdata file.
+const prologueSize = 32
+
+// maxTornReadRetries is the max number of retries to read all entries when
+// the JVM adds entries while they are read.
+const maxTornReadRetries = 3
+
+func (p *Process) readAll() ([]PerfDataEntry, error) {
+	f, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []PerfDataEntry
+	for retries := 0; ; retries++ {
+		result, err = p.readAllEntries(f)
+		if err != nil {
+			return nil, err
+		}
+		torn, err := p.isTorn(f)
+		if err != nil {
+			return nil, err
+		}
+		if !torn {
+			break
+		}
+		if retries == maxTornReadRetries {
+			logp.Debug(DEBUG_SELECTOR, "Entries of %v are changed while they are read, using the last read", p.hsPerfDataPath)
+			break
+		}
+		statTornReadRetries.Add(1)
+	}
+	p.names.Check(result)
+	result = p.filter.FilterEntries(result)
+	p.redactor.RedactEntries(result)
+
+	for _, entry := range result {
+		if entry.DataVariability == VariabilityConstant {
+			p.constantEntries = append(p.constantEntries, entry)
+			if entry.DataType == 'J' {
+				p.constants[entry.EntryName] = entry.LongValue
+			} else {
+				p.constantStrings[entry.EntryName] = entry.StringValue
+			}
+		}
+	}
+	p.labels = p.labeler.Labels(p.constantStrings)
+
+	return result, nil
+}
+
+func (p *Process) readAllEntries(f *os.File) ([]PerfDataEntry, error) {
+	f.Seek(0, os.SEEK_SET)
+	err := p.parser.ReadPrologue(f)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Seek(int64(p.parser.Prologue.EntryOffset), os.SEEK_SET)
+	result, err := p.parser.ReadAllEntry(f)
+	if err != nil {
+		return nil, err
+	}
+	statBytesRead.Add(prologueSize + p.parser.BytesRead)
+	return result, nil
+}
+
+// isTorn reads the prologue again, and returns true if entries are added
+// since the prologue is read before entries. ModTimeStamp is not compared,
+// because the StatSampler of HotSpot updates it at every sampling (50ms by
+// default) without changing entries.
+func (p *Process) isTorn(f *os.File) (bool, error) {
+	before := p.parser.Prologue
+	f.Seek(0, os.SEEK_SET)
+	if err := p.parser.ReadPrologue(f); err != nil {
+		return false, err
+	}
+	statBytesRead.Add(prologueSize)
+	after := p.parser.Prologue
+	return after.Used != before.Used || after.NumEntries != before.NumEntries, nil
+}
+
+func (p *Process) readCached(selected func(name string) bool) ([]PerfDataEntry, error) {
+	f, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// read the prologue again to get the current ModTimeStamp
+	err = p.parser.ReadPrologue(f)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.parser.ReadSelectedEntry(f, selected)
+	statBytesRead.Add(prologueSize + p.parser.BytesRead)
+	if err != nil {
+		return nil, err
+	}
+	p.redactor.RedactEntries(result)
+	return result, nil
+}
+
+// recreated returns true if the JVM has been re-created with the same pid
+// since the first read, i.e. sun.rt.createVmBeginTime in the entries is
+// changed.
+func (p *Process) recreated(entries []PerfDataEntry) bool {
+	if p.id == "" {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.EntryName == createVMBeginTime {
+			return JVMID(p.Pid, map[string]int64{createVMBeginTime: entry.LongValue}) != p.id
+		}
+	}
+	return false
+}
+
+// reset forgets entries, constants and the ID of the previous JVM, so that
+// all entries are read again.
+func (p *Process) reset() {
+	p.parser = &HSPerfData{
+		ForceCachedEntryName: p.parser.ForceCachedEntryName,
+		Filter:               p.parser.Filter,
+	}
+	p.id = ""
+	p.labels = nil
+	p.isFirst = true
+	p.constants = make(map[string]int64)
+	p.constantStrings = make(map[string]string)
+	p.constantEntries = nil
+}
+
+// Read reads counters of the process. If the first read fails, all entries
+// are read again at the next read.
+func (p *Process) Read() (*Snapshot, error) {
+	return p.ReadSelected(nil)
+}
+
+// ReadSelected reads counters of the process like Read, but only selected
+// entries (and sun.rt.createVmBeginTime) are read after the first read. All
+// entries are read if selected is nil. If the JVM has been re-created with
+// the same pid, the process is attached again and all entries are read, so
+// the snapshot has another ID.
+func (p *Process) ReadSelected(selected func(name string) bool) (*Snapshot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := time.Now()
+	first := p.isFirst
+	var entries []PerfDataEntry
+	var err error
+	if first {
+		entries, err = p.readAll()
+		p.isFirst = err != nil // retry to read all entries at next read
+	} else {
+		entries, err = p.readCached(withBeginTime(selected))
+		if err == nil && p.recreated(entries) {
+			logp.Info("JVM of pid %v is re-created, attaching it again", p.Pid)
+			p.store.SetStatus(p.id, p.Pid, StatusDetached, time.Now())
+			p.reset()
+			first = true
+			entries, err = p.readAll()
+			p.isFirst = err != nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	statReads.Add(1)
+	countParse(p.Pid, time.Since(start))
+
+	s := newSnapshot(entries, time.Now())
+	if first {
+		// sun.rt.createVmBeginTime is a variable counter
+		p.id = JVMID(p.Pid, s.Longs)
+		p.store.SetStatus(p.id, p.Pid, StatusAttached, s.Time)
+	}
+	s.ID = p.id
+	s.ModTimeStamp = p.parser.Prologue.ModTimeStamp
+	s.Constants = p.constants
+	s.ConstantStrings = p.constantStrings
+	s.Labels = p.labels
+	s.constantEntries = p.constantEntries
+	for name, value := range p.constants {
+		if _, exists := s.Longs[name]; !exists {
+			s.Longs[name] = value
+		}
+	}
+	for name, value := range p.constantStrings {
+		if _, exists := s.Strings[name]; !exists {
+			s.Strings[name] = value
+		}
+	}
+	return s, nil
+}
+
+// withBeginTime returns the selector which selects sun.rt.createVmBeginTime
+// in addition to selected counters to detect the re-creation of the JVM.
+func withBeginTime(selected func(name string) bool) func(name string) bool {
+	if selected == nil {
+		return nil
+	}
+	return func(name string) bool {
+		return name == createVMBeginTime || selected(name)
+	}
+}
+
+// Tracker discovers Java processes and keeps a Process for each of them.
+// It is shared by metricsets in the hotspot module which have the same
+// config (see SharedTracker), so Java processes are discovered and read once
+// in a period, and the snapshots are handed out to each metricset.
+type Tracker struct {
+	pid     string
+	options ProcessOptions
+	maxAge  time.Duration  // max age of snapshots which are handed out without reading again
+	period  time.Duration  // period of metricsets which share the tracker
+	groups  *counterGroups // nil if no counter groups are configured
+
+	mu          sync.Mutex
+	procs       map[string]*Process       // PID to Process map
+	schedules   map[string]*groupSchedule // PID to the schedule of counter groups
+	prefixes    []string                  // counters which are used by subscribers
+	allCounters bool                      // true if a subscriber uses all counters
+	snapshots   map[string]*Snapshot      // PID to the snapshot of the last update
+	errors      []error                   // errors of the last update
+	updated     time.Time
+}
+
+// NewTracker creates a Tracker for the pid, force_collect, redaction,
+// labels, counter filters, counter groups and the state store in the config.
+// Processes are read at every update.
+func NewTracker(config *Config) *Tracker {
+	return &Tracker{
+		pid: config.Pid,
+		options: ProcessOptions{
+			ForceCollect: config.ForceCachedEntries,
+			Redactor:     NewRedactor(&config.Redaction),
+			Labeler:      NewLabeler(config.Labels),
+			Filter:       NewCounterFilter(&config.Counters),
+			Names:        NewNameValidator(config),
+			Store:        OpenStore(&config.State),
+		},
+		groups:    newCounterGroups(config.CounterGroups),
+		procs:     make(map[string]*Process),
+		schedules: make(map[string]*groupSchedule),
+	}
+}
+
+var (
+	trackersMu sync.Mutex
+	trackers   = make(map[string]*Tracker) // tracker key to the shared tracker
+)
+
+// SharedTracker returns the Tracker which is shared by metricsets with the
+// same config and period. Snapshots are handed out without reading processes
+// again until half of the period has elapsed since the last update, so
+// metricsets which fetch at the same time share one read.
+func SharedTracker(config *Config, period time.Duration) *Tracker {
+	key := trackerKey(config, period)
+
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	if t, exists := trackers[key]; exists {
+		return t
+	}
+
+	t := NewTracker(config)
+	t.maxAge = period / 2
+	t.period = period
+	trackers[key] = t
+	return t
+}
+
+// trackerKey identifies a Tracker by options in the config which affect
+// discovery and reads.
+func trackerKey(config *Config, period time.Duration) string {
+	key, _ := json.Marshal(struct {
+		Pid          string
+		ForceCollect []string
+		Redaction    RedactionConfig
+		Labels       []LabelRule
+		Counters     FilterConfig
+		Groups       []GroupRule
+		State        StateConfig
+		Period       time.Duration
+	}{
+		config.Pid,
+		config.ForceCachedEntries,
+		config.Redaction,
+		config.Labels,
+		config.Counters,
+		config.CounterGroups,
+		config.State,
+		period,
+	})
+	return string(key)
+}
+
+// Store returns the state store, or nil if it is disabled.
+func (t *Tracker) Store() *Store {
+	return t.options.Store
+}
+
+// Subscribe registers name prefixes (with '.' or '/') of counters which a
+// metricset uses. If counter groups are configured, counters of a group are
+// read only when the group is due unless a subscriber uses them. All counters
+// are read at every update if prefixes is nil.
+func (t *Tracker) Subscribe(prefixes []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if prefixes == nil {
+		t.allCounters = true
+	}
+	for _, prefix := range prefixes {
+		t.prefixes = append(t.prefixes, strings.Replace(prefix, ".", "/", -1))
+	}
+}
+
+// Processes returns the attached processes.
+func (t *Tracker) Processes() map[string]*Process {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	procs := make(map[string]*Process, len(t.procs))
+	for pid, proc := range t.procs {
+		procs[pid] = proc
+	}
+	return procs
+}
+
+func (t *Tracker) attach(pid string) error {
+	if _, exists := t.procs[pid]; exists {
+		return nil // pid already attached
+	}
+
+	logp.Debug(DEBUG_SELECTOR, "Attaching java process: %v", pid)
+
+	proc, err := NewProcess(pid, &t.options)
+	if err != nil {
+		return err
+	}
+
+	t.procs[pid] = proc
+	countAttach(pid)
+	return nil
+}
+
+func (t *Tracker) detach(pid string) {
+	logp.Debug(DEBUG_SELECTOR, "Detaching java process: %v", pid)
+	if proc, exists := t.procs[pid]; exists {
+		t.options.Store.SetStatus(proc.id, pid, StatusDetached, time.Now())
+	}
+	delete(t.procs, pid)
+	delete(t.schedules, pid)
+	countDetach(pid)
+}
+
+// Update attaches running Java processes, detaches processes which are no
+// longer running and reads all attached processes. If the configured pid is
+// not 0, only that pid is attached. It returns the snapshot of each process
+// which is read successfully and errors of the update. If the tracker has
+// been updated recently by another metricset, the snapshots and errors of
+// that update are returned. Snapshots are shared, so they must not be
+// modified.
+func (t *Tracker) Update() (map[string]*Snapshot, *multierror.MultiError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if now := time.Now(); now.Sub(t.updated) >= t.maxAge {
+		t.update(now)
+	}
+
+	errors := new(multierror.MultiError)
+	for _, err := range t.errors {
+		errors.Append(err)
+	}
+	snapshots := make(map[string]*Snapshot, len(t.snapshots))
+	for pid, snapshot := range t.snapshots {
+		snapshots[pid] = snapshot
+	}
+	return snapshots, errors
+}
+
+// update discovers and reads processes, and saves snapshots in the state
+// store. The state file is written if it is changed since the previous
+// update. t.mu must be held.
+func (t *Tracker) update(now time.Time) {
+	if err := t.options.Store.Flush(now); err != nil {
+		logp.Warn("Could not write the state of JVMs (%v)", err)
+	}
+
+	t.errors = nil
+	start := time.Now()
+	if err := t.discover(); err != nil {
+		t.errors = append(t.errors, err)
+	}
+	statDiscoveries.Add(1)
+	statDiscoveryDuration.Set(int64(time.Since(start) / time.Microsecond))
+
+	t.snapshots = make(map[string]*Snapshot, len(t.procs))
+	for pid, proc := range t.procs {
+		schedule, exists := t.schedules[pid]
+		if !exists {
+			schedule = newGroupSchedule(t.groups, t.period)
+			t.schedules[pid] = schedule
+		}
+		due := schedule.due(now)
+		snapshot, err := proc.ReadSelected(t.selector(due))
+		if err != nil {
+			t.errors = append(t.errors, err)
+			continue
+		}
+		schedule.commit(snapshot.ID, due, now)
+		snapshot.groups = due
+		t.snapshots[pid] = snapshot
+		t.options.Store.SaveSnapshot(snapshot)
+	}
+	t.updated = now
+}
+
+// selector returns the function which selects counters to read at this
+// update: counters which do not belong to any group, counters of due groups
+// and counters which are used by subscribers. It returns nil if all counters
+// are read. t.mu must be held.
+func (t *Tracker) selector(due []bool) func(name string) bool {
+	if t.groups == nil || t.allCounters {
+		return nil
+	}
+	return func(name string) bool {
+		if i := t.groups.group(name); i < 0 || due[i] {
+			return true
+		}
+		for _, prefix := range t.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (t *Tracker) discover() error {
+	if t.pid != "0" {
+		logp.Debug(DEBUG_SELECTOR, "Fetching data for only one pid: %v", t.pid)
+		return t.attach(t.pid)
+	}
+
+	// need to look for Java Processes
+	logp.Debug(DEBUG_SELECTOR, "Fetching data for multiple java processes")
+	runningPids, err := GetHSPerfPids()
+	if err != nil {
+		return err
+	}
+	logp.Debug(DEBUG_SELECTOR, "Found %v running java processes", len(runningPids))
+
+	running := make(map[string]bool, len(runningPids))
+	stale := make(map[string]bool)
+	for _, pid := range runningPids {
+		running[pid] = true
+		if !ProcessAlive(pid) {
+			stale[pid] = true // the file is left by a killed JVM
+		}
+		if err := t.attach(pid); err != nil {
+			logp.Err("Could not attach java process with pid: %v (%v)", pid, err)
+			// continue with other processes
+		}
+	}
+
+	countStale(t, stale)
+
+	// detach any proc that is no longer running
+	for attachedPid := range t.procs {
+		if !running[attachedPid] {
+			t.detach(attachedPid)
+		}
+	}
+	return nil
+}
+
+// Sampler hands out snapshots of all processes which are attached by the
+// shared Tracker and keeps the previous snapshot of each process to calculate
+// differences and rates. The previous snapshot is restored from the state
+// store at the first read after hsbeat restarts.
+type Sampler struct {
+	tracker  *Tracker
+	previous map[string]*Snapshot // PID to the previous snapshot
+}
+
+// NewSampler creates a Sampler of the metricset for the config. prefixes
+// are name prefixes of counters which the metricset uses (see
+// Tracker.Subscribe).
+func NewSampler(base mb.BaseMetricSet, config *Config, prefixes []string) *Sampler {
+	tracker := SharedTracker(config, base.Module().Config().Period)
+	tracker.Subscribe(prefixes)
+	return &Sampler{
+		tracker:  tracker,
+		previous: make(map[string]*Snapshot),
+	}
+}
+
+// Store returns the state store, or nil if it is disabled.
+func (s *Sampler) Store() *Store {
+	return s.tracker.Store()
+}
+
+// Sample updates the tracker and calls fn with the current and the previous
+// snapshot of each process. The previous snapshot is nil at the first read.
+// Errors in each process are accumulated and returned, so the caller can ship
+// events of other processes. Snapshots must not be modified by fn.
+func (s *Sampler) Sample(fn func(pid string, current, previous *Snapshot)) *multierror.MultiError {
+	snapshots, errors := s.tracker.Update()
+
+	procs := s.tracker.Processes()
+	for pid := range s.previous {
+		if _, exists := procs[pid]; !exists {
+			delete(s.previous, pid)
+		}
+	}
+
+	for pid, snapshot := range snapshots {
+		previous, exists := s.previous[pid]
+		if !exists {
+			previous = s.Store().Restore(snapshot)
+		} else if previous.ID != snapshot.ID {
+			previous = nil // the JVM is re-created with the same pid
+		}
+		fn(pid, snapshot, previous)
+		s.previous[pid] = snapshot
+	}
+
+	return errors
+}
+
+// PreviousLongs returns long values and the time of the previous snapshot.
+// They are nil and zero if the snapshot is nil.
+func PreviousLongs(previous *Snapshot) (map[string]int64, time.Time) {
+	if previous == nil {
+		return nil, time.Time{}
+	}
+	return previous.Longs, previous.Time
+}
@@ -0,0 +1,75 @@
+package hsperfdata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSharedTracker(t *testing.T) {
+	config := DefaultConfig()
+	config.Pid = "999999999"
+	tracker := SharedTracker(&config, time.Hour)
+	if SharedTracker(&config, time.Hour) != tracker {
+		t.Errorf("metricsets with the same config should share the tracker")
+	}
+	other := config
+	other.Pid = "999999998"
+	if SharedTracker(&other, time.Hour) == tracker {
+		t.Errorf("metricsets with another pid should not share the tracker")
+	}
+
+	discoveries := statDiscoveries.Value()
+	_, errors := tracker.Update()
+	assertEquals(t, 1, errors.Count()) // the pid has no hsperfdata file
+	_, errors = tracker.Update()
+	assertEquals(t, 1, errors.Count()) // errors are handed out with snapshots
+	assertEquals(t, discoveries+1, statDiscoveries.Value())
+}
+
+func TestSnapshotWithConstants(t *testing.T) {
+	constant := PerfDataEntry{EntryName: "c", DataType: 'J', DataVariability: VariabilityConstant, LongValue: 1}
+	s := newSnapshot([]PerfDataEntry{longEntry("a", 2)}, time.Now())
+	s.constantEntries = []PerfDataEntry{constant}
+
+	c := s.withConstants()
+	assertEquals(t, 2, len(c.Entries))
+	assertEquals(t, "c", c.Entries[0].EntryName)
+	assertEquals(t, 1, len(s.Entries))
+}
+
+func TestSnapshotSelectEntries(t *testing.T) {
+	s := newSnapshot([]PerfDataEntry{longEntry("a", 1), longEntry("b", 2), stringEntry("c", "x")}, time.Now())
+	s.Constants = map[string]int64{"d": 3}
+
+	c := s.selectEntries(func(name string) bool { return name == "a" })
+	assertEquals(t, 1, len(c.Entries))
+	assertEquals(t, int64(1), c.Longs["a"])
+	assertEquals(t, int64(3), c.Longs["d"])
+	_, exists := c.Longs["b"]
+	assertEquals(t, false, exists)
+	assertEquals(t, 3, len(s.Entries))
+}
+
+func TestProcessRecreated(t *testing.T) {
+	p := &Process{Pid: "1", parser: &HSPerfData{}}
+	assertEquals(t, false, p.recreated([]PerfDataEntry{longEntry(createVMBeginTime, 100)})) // not identified
+
+	p.id = "1@100"
+	assertEquals(t, false, p.recreated([]PerfDataEntry{longEntry(createVMBeginTime, 100)}))
+	assertEquals(t, false, p.recreated([]PerfDataEntry{longEntry("a", 1)}))
+	assertEquals(t, true, p.recreated([]PerfDataEntry{longEntry(createVMBeginTime, 200)}))
+
+	p.reset()
+	assertEquals(t, "", p.id)
+	assertEquals(t, true, p.isFirst)
+}
+
+func TestWithBeginTime(t *testing.T) {
+	if withBeginTime(nil) != nil {
+		t.Errorf("all entries should be read without a selector")
+	}
+	selected := withBeginTime(func(name string) bool { return name == "a" })
+	assertEquals(t, true, selected("a"))
+	assertEquals(t, true, selected(createVMBeginTime))
+	assertEquals(t, false, selected("b"))
+}
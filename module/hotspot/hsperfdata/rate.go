@@ -0,0 +1,199 @@
+package hsperfdata
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Sources of the elapsed time between two fetches.
+const (
+	clockHRT  = "hrt"
+	clockWall = "wallclock"
+)
+
+// gapFactor is the ratio of the elapsed time to the period which is regarded
+// as a gap, i.e. at least one fetch has been skipped.
+const gapFactor = 1.5
+
+// Interval is the elapsed time between the previous and the current fetch.
+type Interval struct {
+	Seconds float64
+	Source  string
+	Gap     bool
+}
+
+// Elapsed returns the interval between the previous and the current fetch.
+// sun.os.hrt.ticks is used if it is available because it is updated by
+// the StatSampler in HotSpot. Otherwise the wall-clock time is used.
+// The interval is zero at the first fetch (previous is nil).
+func Elapsed(current, previous map[string]int64, now, previousTime time.Time, period time.Duration) Interval {
+	if previous == nil {
+		return Interval{}
+	}
+
+	result := Interval{Source: clockWall}
+	freq := current[hrtFrequency]
+	cur, curOk := current[hrtTicks]
+	prev, prevOk := previous[hrtTicks]
+	if freq > 0 && curOk && prevOk && cur > prev {
+		result.Seconds = float64(cur-prev) / float64(freq)
+		result.Source = clockHRT
+	} else {
+		result.Seconds = now.Sub(previousTime).Seconds()
+	}
+
+	if period > 0 {
+		result.Gap = result.Seconds > period.Seconds()*gapFactor
+	}
+
+	return result
+}
+
+func (p *ProcStats) elapsed(current map[string]int64, now time.Time) Interval {
+	return Elapsed(current, p.previousData, now, p.previousTime, p.period)
+}
+
+// Rate returns the per-second rate of a monotonic counter. It returns false
+// if the interval is not available (first fetch) or the counter goes
+// backwards (counter reset) because the rate would be meaningless.
+func (i Interval) Rate(cur, prev int64) (float64, bool) {
+	if i.Seconds <= 0 || cur < prev {
+		return 0, false
+	}
+	return float64(cur-prev) / i.Seconds, true
+}
+
+// CounterDelta returns the increase of a monotonic counter since the
+// previous value. If the counter goes backwards, i.e. it is reset by the
+// restart of the JVM which reuses the PID, a torn read or a wraparound of a
+// 32-bit counter, the current value is regarded as the increase since the
+// reset, and reset is true.
+func CounterDelta(cur, prev int64) (delta int64, reset bool) {
+	if cur < prev {
+		return cur, true
+	}
+	return cur - prev, false
+}
+
+// ToMapStr returns the interval as the "interval" field of events.
+func (i Interval) ToMapStr() common.MapStr {
+	return common.MapStr{
+		"ms":     i.Seconds * 1000,
+		"source": i.Source,
+		"gap":    i.Gap,
+	}
+}
+
+// Resets applies the counter_reset policy (ResetValue or ResetNull) to
+// counters which go backwards in an event, and records their names. A nil
+// Resets applies ResetValue and records nothing.
+type Resets struct {
+	policy   string
+	counters []string
+}
+
+// NewResets creates Resets for the counter_reset policy.
+func NewResets(policy string) *Resets {
+	return &Resets{policy: policy}
+}
+
+// Add records the reset of the counter, and returns true if the difference
+// of the counter should be null.
+func (r *Resets) Add(name string) bool {
+	if r == nil {
+		return false
+	}
+	r.counters = append(r.counters, name)
+	return r.policy == ResetNull
+}
+
+// AddTo adds `reset: true` and the names of counters which are reset in
+// `reset_counters` to the event if any counter is reset.
+func (r *Resets) AddTo(event common.MapStr) {
+	if r == nil || len(r.counters) == 0 {
+		return
+	}
+	event["reset"] = true
+	event["reset_counters"] = r.counters
+}
+
+// CounterMapStr builds the total value of a cumulative counter, the
+// difference from the previous fetch and the per-second rate. The difference
+// and the rate are omitted at the first fetch. When the counter goes
+// backwards, it is recorded in resets, and the difference and the rate are
+// calculated from the increase since the reset (see CounterDelta) or null by
+// the counter_reset policy. It returns false if the counter is not
+// available.
+func CounterMapStr(name string, current, previous map[string]int64, elapsed Interval, resets *Resets) (common.MapStr, bool) {
+	total, ok := current[name]
+	if !ok {
+		return nil, false
+	}
+
+	result := common.MapStr{"total": total}
+	prev, exists := previous[name]
+	if !exists {
+		return result, true
+	}
+	diff, reset := CounterDelta(total, prev)
+	if reset && resets.Add(name) {
+		result["diff"] = nil
+		result["rate"] = nil
+		return result, true
+	}
+	result["diff"] = diff
+	if rate, ok := elapsed.Rate(prev+diff, prev); ok {
+		result["rate"] = rate
+	}
+	return result, true
+}
+
+// TimeMapStr builds the total time of a tick counter in milliseconds, the
+// time since the previous fetch and its share of the elapsed time. When the
+// counter goes backwards, it is recorded in resets, and the time and the
+// share are calculated from the time since the reset or null by the
+// counter_reset policy. It returns false if the counter or
+// sun.os.hrt.frequency is not available.
+func TimeMapStr(name string, current, previous map[string]int64, elapsed Interval, resets *Resets) (common.MapStr, bool) {
+	return timeMapStr(name, current, previous, elapsed, resets, func(ticks int64) (float64, bool) {
+		return TicksToMillis(ticks, current)
+	})
+}
+
+// NanosTimeMapStr is TimeMapStr of a counter in nanoseconds (see
+// UnitsNanoseconds).
+func NanosTimeMapStr(name string, current, previous map[string]int64, elapsed Interval, resets *Resets) (common.MapStr, bool) {
+	return timeMapStr(name, current, previous, elapsed, resets, func(nanos int64) (float64, bool) {
+		return float64(nanos) / 1e6, true
+	})
+}
+
+func timeMapStr(name string, current, previous map[string]int64, elapsed Interval, resets *Resets, toMillis func(int64) (float64, bool)) (common.MapStr, bool) {
+	value, ok := current[name]
+	if !ok {
+		return nil, false
+	}
+	total, ok := toMillis(value)
+	if !ok {
+		return nil, false
+	}
+
+	result := common.MapStr{"total": common.MapStr{"ms": total}}
+	prev, exists := previous[name]
+	if !exists {
+		return result, true
+	}
+	delta, reset := CounterDelta(value, prev)
+	if reset && resets.Add(name) {
+		result["diff"] = nil
+		result["pct"] = nil
+		return result, true
+	}
+	diff, _ := toMillis(delta)
+	result["diff"] = common.MapStr{"ms": diff}
+	if elapsed.Seconds > 0 {
+		result["pct"] = diff / 1000 / elapsed.Seconds
+	}
+	return result, true
+}
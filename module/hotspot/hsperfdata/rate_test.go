@@ -0,0 +1,177 @@
+package hsperfdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestElapsedUsesHRTTicks(t *testing.T) {
+	now := time.Now()
+	p := &ProcStats{
+		previousData: map[string]int64{hrtTicks: 1000},
+		previousTime: now.Add(-5 * time.Second),
+		period:       time.Second,
+	}
+
+	i := p.elapsed(map[string]int64{hrtFrequency: 1000, hrtTicks: 2000}, now)
+	assertEquals(t, 1.0, i.Seconds)
+	assertEquals(t, clockHRT, i.Source)
+	assertEquals(t, false, i.Gap)
+}
+
+func TestElapsedFallsBackToWallClock(t *testing.T) {
+	now := time.Now()
+	p := &ProcStats{
+		previousData: map[string]int64{},
+		previousTime: now.Add(-3 * time.Second),
+		period:       time.Second,
+	}
+
+	i := p.elapsed(map[string]int64{}, now)
+	assertEquals(t, 3.0, i.Seconds)
+	assertEquals(t, clockWall, i.Source)
+	assertEquals(t, true, i.Gap)
+}
+
+func TestElapsedAtFirstFetch(t *testing.T) {
+	p := &ProcStats{}
+	i := p.elapsed(map[string]int64{hrtFrequency: 1000, hrtTicks: 2000}, time.Now())
+	assertEquals(t, 0.0, i.Seconds)
+}
+
+func TestRate(t *testing.T) {
+	i := Interval{Seconds: 2}
+
+	rate, ok := i.Rate(300, 100)
+	assertEquals(t, true, ok)
+	assertEquals(t, 100.0, rate)
+
+	_, ok = i.Rate(100, 300) // counter reset
+	assertEquals(t, false, ok)
+
+	_, ok = Interval{}.Rate(300, 100) // first fetch
+	assertEquals(t, false, ok)
+}
+
+func TestCounterMapStr(t *testing.T) {
+	i := Interval{Seconds: 2}
+	cur := map[string]int64{"a": 300}
+
+	counter, ok := CounterMapStr("a", cur, map[string]int64{"a": 100}, i, nil)
+	assertEquals(t, true, ok)
+	assertEquals(t, int64(300), counter["total"])
+	assertEquals(t, int64(200), counter["diff"])
+	assertEquals(t, 100.0, counter["rate"])
+
+	counter, _ = CounterMapStr("a", cur, nil, Interval{}, nil)
+	assertEquals(t, nil, counter["diff"])
+
+	_, ok = CounterMapStr("b", cur, nil, i, nil)
+	assertEquals(t, false, ok)
+
+	// counter reset
+	resets := NewResets(ResetValue)
+	counter, _ = CounterMapStr("a", cur, map[string]int64{"a": 500}, i, resets)
+	assertEquals(t, int64(300), counter["diff"])
+	assertEquals(t, 150.0, counter["rate"])
+	event := common.MapStr{}
+	resets.AddTo(event)
+	assertEquals(t, true, event["reset"])
+	assertEquals(t, "a", event["reset_counters"].([]string)[0])
+
+	resets = NewResets(ResetNull)
+	counter, _ = CounterMapStr("a", cur, map[string]int64{"a": 500}, i, resets)
+	if diff, exists := counter["diff"]; !exists || diff != nil {
+		t.Errorf("diff should be null: %v", diff)
+	}
+	if rate, exists := counter["rate"]; !exists || rate != nil {
+		t.Errorf("rate should be null: %v", rate)
+	}
+	assertEquals(t, 1, len(resets.counters))
+}
+
+func TestCounterDelta(t *testing.T) {
+	delta, reset := CounterDelta(300, 100)
+	assertEquals(t, int64(200), delta)
+	assertEquals(t, false, reset)
+
+	delta, reset = CounterDelta(30, 4294967290) // restart or 32-bit wraparound
+	assertEquals(t, int64(30), delta)
+	assertEquals(t, true, reset)
+}
+
+func newResetProc(mode string) *ProcStats {
+	config := DefaultConfig()
+	config.CounterReset = mode
+	return &ProcStats{pid: "1", config: &config}
+}
+
+func TestBuildMapStrWithReset(t *testing.T) {
+	p := newResetProc(ResetValue)
+	now := time.Now()
+
+	p.buildMapStr(newSnapshot([]PerfDataEntry{monotonicEntry("count", 100), longEntry("heap", 50)}, now))
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{monotonicEntry("count", 10), longEntry("heap", 20)}, now.Add(time.Second)))
+	assertEquals(t, int64(10), event["count/diff"])
+	assertEquals(t, 10.0, event["count/rate"])
+	assertEquals(t, int64(-30), event["heap/diff"]) // variable counters can go down
+	assertEquals(t, true, event["reset"])
+	resets := event["reset_counters"].([]string)
+	assertEquals(t, 1, len(resets))
+	assertEquals(t, "count", resets[0])
+
+	event = p.buildMapStr(newSnapshot([]PerfDataEntry{monotonicEntry("count", 15), longEntry("heap", 20)}, now.Add(2*time.Second)))
+	assertEquals(t, int64(5), event["count/diff"])
+	assertEquals(t, nil, event["reset"])
+}
+
+func TestBuildMapStrWithResetNull(t *testing.T) {
+	p := newResetProc(ResetNull)
+	now := time.Now()
+
+	p.buildMapStr(newSnapshot([]PerfDataEntry{monotonicEntry("count", 100)}, now))
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{monotonicEntry("count", 10)}, now.Add(time.Second)))
+	assertEquals(t, int64(10), event["count"])
+	if diff, exists := event["count/diff"]; !exists || diff != nil {
+		t.Errorf("count/diff should be null: %v", diff)
+	}
+	if rate, exists := event["count/rate"]; !exists || rate != nil {
+		t.Errorf("count/rate should be null: %v", rate)
+	}
+	assertEquals(t, true, event["reset"])
+}
+
+func TestConfigValidateCounterReset(t *testing.T) {
+	config := DefaultConfig()
+	if err := config.Validate(); err != nil {
+		t.Errorf("default config should be valid: %v", err)
+	}
+	config.CounterReset = "zero"
+	if err := config.Validate(); err == nil {
+		t.Errorf("unknown counter_reset should be rejected")
+	}
+}
+
+func TestTimeMapStr(t *testing.T) {
+	i := Interval{Seconds: 2}
+	cur := map[string]int64{hrtFrequency: 1000, "a": 900}
+
+	time, ok := TimeMapStr("a", cur, map[string]int64{"a": 500}, i, nil)
+	assertEquals(t, true, ok)
+	assertEquals(t, 900.0, getValue(t, time, "total.ms"))
+	assertEquals(t, 400.0, getValue(t, time, "diff.ms"))
+	assertEquals(t, 0.2, getValue(t, time, "pct"))
+
+	_, ok = TimeMapStr("a", map[string]int64{"a": 900}, nil, i, nil)
+	assertEquals(t, false, ok)
+
+	// counter reset
+	resets := NewResets(ResetNull)
+	time, _ = TimeMapStr("a", cur, map[string]int64{"a": 1500}, i, resets)
+	assertEquals(t, nil, time["diff"])
+	assertEquals(t, "a", resets.counters[0])
+	time, _ = TimeMapStr("a", cur, map[string]int64{"a": 1500}, i, NewResets(ResetValue))
+	assertEquals(t, 900.0, getValue(t, time, "diff.ms"))
+}
@@ -0,0 +1,228 @@
+package hsperfdata_test
+
+import (
+	"expvar"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+// testCounters returns 2 constants, sun.os.hrt.ticks, 20 sun.cls counters and
+// sun.gc.cause.
+func testCounters(ticks, cls int64) []hsperftest.Counter {
+	counters := []hsperftest.Counter{
+		{Name: "sun.os.hrt.frequency", Value: int64(1000000000)},
+		{Name: "sun.os.hrt.ticks", Value: ticks},
+		{Name: "sun.rt.javaCommand", Value: "org.example.Main"},
+	}
+	for i := 0; i < 20; i++ {
+		counters = append(counters, hsperftest.Counter{Name: "sun.cls.counter" + strconv.Itoa(i), Value: cls * int64(i)})
+	}
+	return append(counters, hsperftest.Counter{Name: "sun.gc.cause", Value: "No GC"})
+}
+
+func readAll(t *testing.T, path string) (*hsperfdata.HSPerfData, *os.File) {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := &hsperfdata.HSPerfData{}
+	if err := parser.ReadPrologue(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(int64(parser.Prologue.EntryOffset), os.SEEK_SET)
+	entries, err := parser.ReadAllEntry(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 24 {
+		t.Fatalf("24 entries are expected, but %v", len(entries))
+	}
+	return parser, f
+}
+
+func TestReadSelectedEntry(t *testing.T) {
+	path := hsperftest.WriteFile(t, "100", testCounters(1000, 10), 1)
+	parser, f := readAll(t, path)
+	f.Close()
+
+	hsperftest.WriteFile(t, "100", testCounters(2000, 20), 2)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	all, err := parser.ReadSelectedEntry(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 22 { // without constants
+		t.Fatalf("22 cached entries are expected, but %v", len(all))
+	}
+	for _, entry := range all {
+		switch entry.EntryName {
+		case "sun/os/hrt/ticks":
+			assertLong(t, 2000, entry)
+		case "sun/cls/counter2":
+			assertLong(t, 40, entry)
+		case "sun/gc/cause":
+			if entry.StringValue != "No GC" {
+				t.Errorf("unexpected value of %v: %v", entry.EntryName, entry.StringValue)
+			}
+		}
+	}
+	allBytes := parser.BytesRead
+
+	selected, err := parser.ReadSelectedEntry(f, func(name string) bool {
+		return !strings.HasPrefix(name, "sun/cls/")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 {
+		t.Fatalf("2 selected entries are expected, but %v", len(selected))
+	}
+	if parser.BytesRead <= 0 || parser.BytesRead >= allBytes {
+		t.Errorf("only selected entries should be read: %v bytes of %v", parser.BytesRead, allBytes)
+	}
+}
+
+func TestIsTorn(t *testing.T) {
+	path := hsperftest.WriteFile(t, "100", testCounters(1000, 10), 1)
+	proc, err := hsperfdata.NewProcess("100", &hsperfdata.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := hsperfdata.ReadAllEntries(proc, f); err != nil {
+		t.Fatal(err)
+	}
+
+	// the StatSampler updates values and ModTimeStamp
+	hsperftest.WriteFile(t, "100", testCounters(2000, 20), 2)
+	torn, err := hsperfdata.IsTorn(proc, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsperftest.AssertEquals(t, false, torn)
+
+	// the JVM adds an entry
+	counters := append(testCounters(3000, 30), hsperftest.Counter{Name: "sun.rt.added", Value: int64(1)})
+	hsperftest.WriteFile(t, "100", counters, 3)
+	torn, err = hsperfdata.IsTorn(proc, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsperftest.AssertEquals(t, true, torn)
+}
+
+func TestBytesReadStat(t *testing.T) {
+	path := hsperftest.WriteFile(t, "100", testCounters(1000, 10), 1)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc, err := hsperfdata.NewProcess("100", &hsperfdata.ProcessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat := expvar.Get("hotspot.read.bytes").(*expvar.Int)
+	before := stat.Value()
+	if _, err := proc.Read(); err != nil {
+		t.Fatal(err)
+	}
+	// the file and the prologue which is read again to detect a torn read
+	hsperftest.AssertEquals(t, info.Size()+32, stat.Value()-before)
+
+	before = stat.Value()
+	if _, err := proc.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if read := stat.Value() - before; read <= 32 || read >= info.Size() {
+		t.Errorf("only the prologue and cached entries should be read: %v bytes", read)
+	}
+}
+
+func TestTrackerReadsDueGroups(t *testing.T) {
+	hsperftest.WriteFile(t, "100", testCounters(1000, 10), 1)
+	config := hsperfdata.DefaultConfig()
+	config.Pid = "100"
+	config.CounterGroups = []hsperfdata.GroupRule{{Name: "cls", Prefixes: []string{"sun.cls."}, Interval: time.Hour}}
+	tracker := hsperfdata.NewTracker(&config)
+
+	snapshots, errors := tracker.Update()
+	if errors.HasErrors() {
+		t.Fatal(errors.String())
+	}
+	hsperftest.AssertEquals(t, int64(10), snapshots["100"].Longs["sun/cls/counter1"])
+
+	hsperftest.WriteFile(t, "100", testCounters(2000, 20), 2)
+	snapshots, _ = tracker.Update()
+	hsperftest.AssertEquals(t, int64(2000), snapshots["100"].Longs["sun/os/hrt/ticks"])
+	if _, exists := snapshots["100"].Longs["sun/cls/counter1"]; exists {
+		t.Errorf("counters of the group should not be read until the interval elapses")
+	}
+
+	// counters of the group are read if another metricset uses them
+	tracker.Subscribe([]string{"sun.cls.counter1"})
+	snapshots, _ = tracker.Update()
+	hsperftest.AssertEquals(t, int64(20), snapshots["100"].Longs["sun/cls/counter1"])
+	if _, exists := snapshots["100"].Longs["sun/cls/counter2"]; exists {
+		t.Errorf("counters of the group which are not used should not be read")
+	}
+}
+
+func TestFilterSharedByProcesses(t *testing.T) {
+	filter := hsperfdata.NewCounterFilter(&hsperfdata.FilterConfig{Exclude: []string{"sun.cls.counter0"}})
+	for _, pid := range []string{"1", "2"} {
+		counters := testCounters(1000, 1)
+		for i := 0; i < 20; i++ {
+			counters = append(counters, hsperftest.Counter{Name: "sun.rt.counter" + pid + "_" + strconv.Itoa(i), Value: int64(i)})
+		}
+		hsperftest.WriteFile(t, pid, counters, 1)
+	}
+
+	// The cache of the filter is updated by both processes at once.
+	var wg sync.WaitGroup
+	for _, pid := range []string{"1", "2"} {
+		wg.Add(1)
+		go func(pid string) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				proc, err := hsperfdata.NewProcess(pid, &hsperfdata.ProcessOptions{Filter: filter})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for j := 0; j < 2; j++ {
+					if _, err := proc.Read(); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(pid)
+	}
+	wg.Wait()
+
+	hsperftest.AssertEquals(t, false, filter.Match("sun/cls/counter0"))
+	hsperftest.AssertEquals(t, true, filter.Match("sun/rt/counter1_0"))
+}
+
+func assertLong(t *testing.T, expected int64, entry hsperfdata.PerfDataEntry) {
+	if entry.LongValue != expected {
+		t.Errorf("%v is expected for %v, but %v", expected, entry.EntryName, entry.LongValue)
+	}
+}
@@ -0,0 +1,187 @@
+package hsperfdata
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// summary is min, max and sum of samples of a variable counter.
+type summary struct {
+	min, max, sum int64
+	count         int
+}
+
+func (s *summary) add(v int64) {
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.sum += v
+	s.count++
+}
+
+// periodStats accumulates samples of a process which are read at the
+// sampling interval between fetches. Variable counters are summarized, and
+// increments of monotonic counters are summed up, so the delta is exact even
+// if the counter is reset in the period.
+type periodStats struct {
+	samples   int
+	variables map[string]*summary
+	deltas    map[string]int64
+	resets    map[string]bool  // monotonic counters which are reset in the period
+	last      map[string]int64 // last values of monotonic counters
+}
+
+func newPeriodStats() *periodStats {
+	return &periodStats{
+		variables: make(map[string]*summary),
+		deltas:    make(map[string]int64),
+		resets:    make(map[string]bool),
+		last:      make(map[string]int64),
+	}
+}
+
+// add adds a sample of long entries.
+func (s *periodStats) add(entries []PerfDataEntry) {
+	s.samples++
+	for _, entry := range entries {
+		if entry.DataType != 'J' {
+			continue
+		}
+		switch entry.DataVariability {
+		case VariabilityVariable:
+			v, exists := s.variables[entry.EntryName]
+			if !exists {
+				v = &summary{}
+				s.variables[entry.EntryName] = v
+			}
+			v.add(entry.LongValue)
+		case VariabilityMonotonic:
+			if last, exists := s.last[entry.EntryName]; exists {
+				delta, reset := CounterDelta(entry.LongValue, last)
+				if reset {
+					s.resets[entry.EntryName] = true
+				}
+				s.deltas[entry.EntryName] += delta
+			}
+			s.last[entry.EntryName] = entry.LongValue
+		}
+	}
+}
+
+// reset starts a new period. Last values of monotonic counters are kept to
+// calculate the delta of the next period.
+func (s *periodStats) reset() {
+	s.samples = 0
+	s.variables = make(map[string]*summary)
+	s.deltas = make(map[string]int64)
+	s.resets = make(map[string]bool)
+}
+
+// delta returns the delta of the monotonic counter in the period. It returns
+// false if the stats is nil or the counter is not sampled twice.
+func (s *periodStats) delta(name string) (int64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	d, ok := s.deltas[name]
+	return d, ok
+}
+
+// isReset returns true if the monotonic counter is reset in the period.
+func (s *periodStats) isReset(name string) bool {
+	return s != nil && s.resets[name]
+}
+
+// addSummary adds `<counter>/min`, `<counter>/max` and `<counter>/avg` of
+// the variable counter to the event. The last value is the counter itself.
+// If the counter is not shipped (changes_only), the summary is added only if
+// the counter changed in the period.
+func (s *periodStats) addSummary(event common.MapStr, name string, shipped bool) {
+	if s == nil {
+		return
+	}
+	v, ok := s.variables[name]
+	if !ok || (!shipped && v.min == v.max) {
+		return
+	}
+	event[name+"/min"] = v.min
+	event[name+"/max"] = v.max
+	event[name+"/avg"] = float64(v.sum) / float64(v.count)
+}
+
+// toMapStr returns the number of samples and the sampling interval.
+func (s *periodStats) toMapStr(interval time.Duration) common.MapStr {
+	return common.MapStr{
+		"samples":  s.samples,
+		"interval": common.MapStr{"ms": float64(interval) / float64(time.Millisecond)},
+	}
+}
+
+// samplingIdlePeriods is the number of periods without a fetch after which
+// sampling stops. Metricbeat does not close a MetricSet when it is stopped
+// (e.g. the module is reloaded), so the sampling goroutine lives only while
+// the MetricSet is fetched. The next fetch starts sampling again.
+const samplingIdlePeriods = 3
+
+// startSampling samples cached entries of processes which have been fetched
+// successfully at least once at the sampling interval until the MetricSet is
+// closed or is not fetched for samplingIdlePeriods. Processes which have not
+// been fetched are skipped so that constants are read and shipped by the
+// first fetch. Counters in counter groups are not sampled, because they are
+// not read at every fetch. m.mu must be held.
+func (m *MetricSet) startSampling() {
+	logp.Info("Sampling hsperfdata counters every %v", m.config.SamplingInterval)
+	m.sampling = true
+	go func() {
+		ticker := time.NewTicker(m.config.SamplingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				logp.Debug(DEBUG_SELECTOR, "Stopped sampling hsperfdata counters")
+				return
+			case now := <-ticker.C:
+				if !m.sample(now) {
+					logp.Debug(DEBUG_SELECTOR, "Stopped sampling hsperfdata counters, not fetched for %v periods", samplingIdlePeriods)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// sample samples processes. It returns false and marks sampling stopped if
+// the MetricSet has not been fetched for samplingIdlePeriods.
+func (m *MetricSet) sample(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.fetched) > samplingIdlePeriods*m.period {
+		m.sampling = false
+		return false
+	}
+
+	procs := m.tracker.Processes()
+	for pid, p := range m.procs {
+		proc, exists := procs[pid]
+		if !exists || p.sampling == nil || p.previousData == nil {
+			continue
+		}
+		var selected func(name string) bool
+		if p.groups != nil {
+			selected = p.groups.ungrouped
+		}
+		snapshot, err := proc.ReadSelected(selected)
+		if err != nil {
+			logp.Debug(DEBUG_SELECTOR, "Could not sample counters of %v (%v)", pid, err)
+			continue
+		}
+		p.sampling.add(snapshot.Entries)
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package hsperfdata
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func monotonicEntry(name string, value int64) PerfDataEntry {
+	return PerfDataEntry{
+		EntryName:       name,
+		DataType:        'J',
+		DataVariability: VariabilityMonotonic,
+		LongValue:       value,
+	}
+}
+
+func newSamplingProc(changesOnly bool) *ProcStats {
+	config := DefaultConfig()
+	config.SamplingInterval = 50 * time.Millisecond
+	config.ChangesOnly = changesOnly
+	return &ProcStats{pid: "1", config: &config, sampling: newPeriodStats()}
+}
+
+func TestPeriodStats(t *testing.T) {
+	s := newPeriodStats()
+	for _, v := range []int64{10, 30, 20} {
+		s.add([]PerfDataEntry{longEntry("heap", v), monotonicEntry("ticks", v)})
+	}
+
+	assertEquals(t, 3, s.samples)
+	assertEquals(t, int64(10), s.variables["heap"].min)
+	assertEquals(t, int64(30), s.variables["heap"].max)
+	assertEquals(t, int64(60), s.variables["heap"].sum)
+
+	// 10 -> 30 is +20, and 30 -> 20 is regarded as a reset to 20
+	delta, ok := s.delta("ticks")
+	assertEquals(t, true, ok)
+	assertEquals(t, int64(40), delta)
+
+	s.reset()
+	assertEquals(t, 0, s.samples)
+	s.add([]PerfDataEntry{monotonicEntry("ticks", 25)})
+	delta, _ = s.delta("ticks")
+	assertEquals(t, int64(5), delta)
+}
+
+func TestNilPeriodStats(t *testing.T) {
+	var s *periodStats
+	_, ok := s.delta("ticks")
+	assertEquals(t, false, ok)
+}
+
+func TestBuildMapStrWithSampling(t *testing.T) {
+	p := newSamplingProc(false)
+	now := time.Now()
+
+	p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("heap", 10), monotonicEntry("count", 100)}, now))
+	p.sampling.add([]PerfDataEntry{longEntry("heap", 50), monotonicEntry("count", 105)})
+	p.sampling.add([]PerfDataEntry{longEntry("heap", 5), monotonicEntry("count", 3)}) // reset
+
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("heap", 15), monotonicEntry("count", 10)}, now.Add(time.Second)))
+	assertEquals(t, int64(15), event["heap"])
+	assertEquals(t, int64(5), event["heap/min"])
+	assertEquals(t, int64(50), event["heap/max"])
+	assertEquals(t, 70.0/3, event["heap/avg"])
+	assertEquals(t, int64(15), event["count/diff"])
+	assertEquals(t, 3, getValue(t, event, "sampling.samples"))
+	assertEquals(t, 50.0, getValue(t, event, "sampling.interval.ms"))
+	assertEquals(t, 0, p.sampling.samples)
+}
+
+func TestChangesOnlyWithSampling(t *testing.T) {
+	p := newSamplingProc(true)
+	now := time.Now()
+
+	p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("a", 10), longEntry("b", 1)}, now))
+	p.sampling.add([]PerfDataEntry{longEntry("a", 90), longEntry("b", 1)})
+
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("a", 10), longEntry("b", 1)}, now.Add(time.Second)))
+	if _, exists := event["a"]; exists {
+		t.Errorf("a should not be shipped because it is not changed")
+	}
+	assertEquals(t, int64(90), event["a/max"])
+	if _, exists := event["b/max"]; exists {
+		t.Errorf("summary of b should not be shipped because it is not changed in the period")
+	}
+}
+
+func TestSamplingSkipsCounterGroups(t *testing.T) {
+	p := newSamplingProc(false)
+	p.groups = newGroupReads(newCounterGroups([]GroupRule{
+		{Name: "cold", Prefixes: []string{"sun/cls/"}, Interval: 10 * time.Second},
+	}))
+	now := time.Now()
+
+	assertEquals(t, false, p.groups.ungrouped("sun/cls/loaded"))
+	assertEquals(t, true, p.groups.ungrouped("heap"))
+
+	p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("heap", 10), longEntry("sun/cls/loaded", 100)}, now))
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("heap", 20), longEntry("sun/cls/loaded", 200)}, now.Add(time.Second)))
+	assertEquals(t, int64(20), event["heap/min"])
+	assertEquals(t, int64(200), event["sun/cls/loaded"])
+	if _, exists := event["sun/cls/loaded/min"]; exists {
+		t.Errorf("counters in counter groups should not be sampled")
+	}
+}
+
+// samplingGoroutines returns the number of running sampling goroutines.
+func samplingGoroutines() int {
+	buf := make([]byte, 1<<20)
+	return strings.Count(string(buf[:runtime.Stack(buf, true)]), "(*MetricSet).startSampling.func")
+}
+
+func TestSamplingStopsWithoutFetch(t *testing.T) {
+	config := DefaultConfig()
+	config.SamplingInterval = time.Millisecond
+	m := &MetricSet{
+		config:  config,
+		period:  10 * time.Millisecond,
+		tracker: NewTracker(&config),
+		procs:   make(map[string]*ProcStats),
+		done:    make(chan struct{}),
+	}
+	before := samplingGoroutines()
+
+	m.mu.Lock()
+	m.fetched = time.Now()
+	m.startSampling()
+	m.mu.Unlock()
+	assertEquals(t, before+1, samplingGoroutines())
+
+	// the goroutine stops after samplingIdlePeriods without a fetch
+	deadline := time.Now().Add(5 * time.Second)
+	for samplingGoroutines() > before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assertEquals(t, before, samplingGoroutines())
+	m.mu.Lock()
+	assertEquals(t, false, m.sampling)
+	m.mu.Unlock()
+}
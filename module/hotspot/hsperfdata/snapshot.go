@@ -0,0 +1,30 @@
+package hsperfdata
+
+import (
+	"time"
+)
+
+// isFullSnapshot returns true if all cached entries should be shipped at this
+// fetch. It is always true unless changes_only is enabled. Otherwise the first
+// fetch and every full_snapshot.periods fetches or full_snapshot.interval are
+// full snapshots.
+func (p *ProcStats) isFullSnapshot(now time.Time) bool {
+	if !p.config.ChangesOnly || p.previousData == nil {
+		p.fetchesSinceFullSnapshot = 0
+		p.lastFullSnapshot = now
+		return true
+	}
+
+	p.fetchesSinceFullSnapshot++
+
+	periods := p.config.FullSnapshotPeriods
+	interval := p.config.FullSnapshotInterval
+	if (periods > 0 && p.fetchesSinceFullSnapshot >= periods) ||
+		(interval > 0 && now.Sub(p.lastFullSnapshot) >= interval) {
+		p.fetchesSinceFullSnapshot = 0
+		p.lastFullSnapshot = now
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package hsperfdata
+
+import (
+	"testing"
+	"time"
+)
+
+func newChangesOnlyProc(periods int, interval time.Duration) *ProcStats {
+	config := DefaultConfig()
+	config.ChangesOnly = true
+	config.FullSnapshotPeriods = periods
+	config.FullSnapshotInterval = interval
+	return &ProcStats{pid: "1", config: &config}
+}
+
+func longEntry(name string, value int64) PerfDataEntry {
+	return PerfDataEntry{
+		EntryName:       name,
+		DataType:        'J',
+		DataVariability: VariabilityVariable,
+		LongValue:       value,
+	}
+}
+
+func stringEntry(name string, value string) PerfDataEntry {
+	return PerfDataEntry{
+		EntryName:       name,
+		DataType:        'B',
+		DataVariability: VariabilityVariable,
+		StringValue:     value,
+	}
+}
+
+func TestChangesOnly(t *testing.T) {
+	p := newChangesOnlyProc(0, 0)
+	now := time.Now()
+
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{
+		longEntry("a", 1), longEntry("b", 1), stringEntry("c", "x"),
+	}, now))
+	assertEquals(t, "full", event["snapshot"])
+	assertEquals(t, int64(1), event["a"])
+	assertEquals(t, "x", event["c"])
+
+	event = p.buildMapStr(newSnapshot([]PerfDataEntry{
+		longEntry("a", 1), longEntry("b", 2), stringEntry("c", "x"),
+	}, now.Add(time.Second)))
+	assertEquals(t, "changes", event["snapshot"])
+	assertEquals(t, nil, event["a"])
+	assertEquals(t, int64(2), event["b"])
+	assertEquals(t, int64(1), event["b/diff"])
+	assertEquals(t, nil, event["c"])
+
+	event = p.buildMapStr(newSnapshot([]PerfDataEntry{
+		longEntry("a", 1), longEntry("b", 2), stringEntry("c", "y"),
+	}, now.Add(2*time.Second)))
+	assertEquals(t, "y", event["c"])
+}
+
+func TestFullSnapshotPeriods(t *testing.T) {
+	p := newChangesOnlyProc(2, 0)
+	now := time.Now()
+
+	assertEquals(t, true, p.isFullSnapshot(now))
+	p.previousData = map[string]int64{}
+	assertEquals(t, false, p.isFullSnapshot(now))
+	assertEquals(t, true, p.isFullSnapshot(now))
+	assertEquals(t, false, p.isFullSnapshot(now))
+}
+
+func TestFullSnapshotInterval(t *testing.T) {
+	p := newChangesOnlyProc(0, time.Minute)
+	now := time.Now()
+
+	assertEquals(t, true, p.isFullSnapshot(now))
+	p.previousData = map[string]int64{}
+	assertEquals(t, false, p.isFullSnapshot(now.Add(30*time.Second)))
+	assertEquals(t, true, p.isFullSnapshot(now.Add(time.Minute)))
+	assertEquals(t, false, p.isFullSnapshot(now.Add(90*time.Second)))
+}
+
+func TestFullSnapshotWithoutChangesOnly(t *testing.T) {
+	config := DefaultConfig()
+	p := &ProcStats{pid: "1", config: &config}
+	now := time.Now()
+
+	p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("a", 1)}, now))
+	event := p.buildMapStr(newSnapshot([]PerfDataEntry{longEntry("a", 1)}, now.Add(time.Second)))
+	assertEquals(t, int64(1), event["a"])
+	assertEquals(t, nil, event["snapshot"])
+}
@@ -0,0 +1,386 @@
+package hsperfdata
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+)
+
+// createVMBeginTime identifies a JVM with the pid.
+const createVMBeginTime = "sun/rt/createVmBeginTime"
+
+// Lifecycle status of a JVM in the state store.
+const (
+	StatusAttached = "attached"
+	StatusDetached = "detached"
+)
+
+// StateConfig configures the state store which keeps the state of each JVM
+// across restarts of hsbeat.
+type StateConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Path of the state file. A relative path is resolved in the data path.
+	Path string `config:"path"`
+
+	// FlushInterval is the min interval to write the state file. Zero writes
+	// it at every fetch if the state is changed.
+	FlushInterval time.Duration `config:"flush_interval" validate:"min=0"`
+
+	// TTL is how long the state of a JVM is kept after it is detached or
+	// updated last time. Older snapshots are not restored.
+	TTL time.Duration `config:"ttl" validate:"min=0"`
+}
+
+// SavedSnapshot is the last snapshot of a JVM which is read by the Tracker.
+// It holds only values which are not constant, because constants are read
+// again when the JVM is attached after hsbeat restarts.
+type SavedSnapshot struct {
+	Time    time.Time         `json:"time"`
+	Longs   map[string]int64  `json:"longs"`
+	Strings map[string]string `json:"strings,omitempty"`
+}
+
+// ShippedConstants is the fingerprint of constants which are shipped by a
+// metricset last time.
+type ShippedConstants struct {
+	Fingerprint string    `json:"fingerprint"`
+	Time        time.Time `json:"time"`
+}
+
+// JVMState is the state of a JVM in the state store. Shipped is keyed by
+// the metricset name.
+type JVMState struct {
+	Pid      string                      `json:"pid"`
+	Status   string                      `json:"status"`
+	Updated  time.Time                   `json:"updated"`
+	Snapshot *SavedSnapshot              `json:"snapshot,omitempty"`
+	Shipped  map[string]ShippedConstants `json:"shipped,omitempty"`
+}
+
+// Store keeps the state of each JVM, keyed by the JVM ID (see Snapshot.ID),
+// in a JSON file like the Filebeat registry. All metricsets which use the
+// same file share a Store. Methods of a nil Store do nothing.
+type Store struct {
+	path          string
+	flushInterval time.Duration
+	ttl           time.Duration
+
+	mu      sync.Mutex
+	jvms    map[string]*JVMState
+	dirty   bool
+	flushed time.Time
+
+	// restored are snapshots which are loaded from the state file. They are
+	// not overwritten by snapshots which are saved after that, so every
+	// metricset restores the values before hsbeat restarted.
+	restored map[string]*SavedSnapshot
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store) // path to the shared store
+)
+
+// OpenStore returns the store for the config. The state file is loaded when
+// it is opened first time. It returns nil if the store is disabled.
+func OpenStore(config *StateConfig) *Store {
+	if !config.Enabled {
+		return nil
+	}
+	path := paths.Resolve(paths.Data, config.Path)
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, exists := stores[path]; exists {
+		return s
+	}
+
+	s := newStore(path, config.FlushInterval, config.TTL)
+	if err := s.load(); err != nil {
+		logp.Warn("Could not load the state of JVMs from %v, starting without it (%v)", path, err)
+	}
+	stores[path] = s
+	return s
+}
+
+func newStore(path string, flushInterval, ttl time.Duration) *Store {
+	return &Store{
+		path:          path,
+		flushInterval: flushInterval,
+		ttl:           ttl,
+		jvms:          make(map[string]*JVMState),
+		restored:      make(map[string]*SavedSnapshot),
+	}
+}
+
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	jvms := make(map[string]*JVMState)
+	if err := json.NewDecoder(f).Decode(&jvms); err != nil {
+		return err
+	}
+	s.jvms = jvms
+	for id, state := range jvms {
+		if state.Snapshot != nil {
+			s.restored[id] = state.Snapshot
+		}
+	}
+	logp.Info("Loaded the state of %v JVMs from %v", len(jvms), s.path)
+	return nil
+}
+
+// state returns the state of the JVM, which is created if update is true.
+// s.mu must be held.
+func (s *Store) state(id string, update bool, now time.Time) *JVMState {
+	state, exists := s.jvms[id]
+	if !update {
+		return state
+	}
+	if !exists {
+		state = &JVMState{}
+		s.jvms[id] = state
+	}
+	if state.Shipped == nil {
+		state.Shipped = make(map[string]ShippedConstants)
+	}
+	state.Updated = now
+	s.dirty = true
+	return state
+}
+
+// SetStatus records the lifecycle status of the JVM. The snapshot which is
+// loaded from the state file is not restored after the JVM is detached.
+func (s *Store) SetStatus(id, pid, status string, now time.Time) {
+	if s == nil || id == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state(id, true, now)
+	state.Pid = pid
+	state.Status = status
+	if status == StatusDetached {
+		delete(s.restored, id)
+	}
+}
+
+// Restore returns the snapshot of the JVM of current which is loaded from
+// the state file, with constants of current. It returns nil if there is no
+// snapshot or it is older than the TTL.
+func (s *Store) Restore(current *Snapshot) *Snapshot {
+	if s == nil || current.ID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved, exists := s.restored[current.ID]
+	if !exists || s.ttl > 0 && current.Time.Sub(saved.Time) > s.ttl {
+		return nil
+	}
+	return &Snapshot{
+		ID:              current.ID,
+		Time:            saved.Time,
+		Longs:           mergeLongs(current.Constants, saved.Longs),
+		Strings:         mergeStrings(current.ConstantStrings, saved.Strings),
+		Constants:       current.Constants,
+		ConstantStrings: current.ConstantStrings,
+		Labels:          current.Labels,
+	}
+}
+
+// SaveSnapshot saves values of the snapshot which are not constant. It is
+// called once for each read of a JVM by the Tracker.
+func (s *Store) SaveSnapshot(snapshot *Snapshot) {
+	if s == nil || snapshot.ID == "" {
+		return
+	}
+	saved := &SavedSnapshot{
+		Time:  snapshot.Time,
+		Longs: make(map[string]int64, len(snapshot.Longs)-len(snapshot.Constants)),
+	}
+	for name, value := range snapshot.Longs {
+		if _, constant := snapshot.Constants[name]; !constant {
+			saved.Longs[name] = value
+		}
+	}
+	for name, value := range snapshot.Strings {
+		if _, constant := snapshot.ConstantStrings[name]; constant {
+			continue
+		}
+		if saved.Strings == nil {
+			saved.Strings = make(map[string]string)
+		}
+		saved.Strings[name] = value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state(snapshot.ID, true, snapshot.Time).Snapshot = saved
+}
+
+// Shipped returns constants of the JVM which are shipped by the metricset
+// last time.
+func (s *Store) Shipped(id, metricset string) (ShippedConstants, bool) {
+	if s == nil || id == "" {
+		return ShippedConstants{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state(id, false, time.Time{})
+	if state == nil {
+		return ShippedConstants{}, false
+	}
+	shipped, exists := state.Shipped[metricset]
+	return shipped, exists
+}
+
+// SaveShipped records constants of the JVM which are shipped by the
+// metricset.
+func (s *Store) SaveShipped(id, metricset string, shipped ShippedConstants) {
+	if s == nil || id == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state(id, true, shipped.Time).Shipped[metricset] = shipped
+}
+
+// Flush writes the state file if the state is changed and FlushInterval
+// has elapsed since the last write. JVMs which are not updated for the TTL,
+// e.g. detached JVMs, are removed.
+func (s *Store) Flush(now time.Time) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty || now.Sub(s.flushed) < s.flushInterval {
+		return nil
+	}
+
+	for id, state := range s.jvms {
+		if s.ttl > 0 && now.Sub(state.Updated) > s.ttl {
+			delete(s.jvms, id)
+		}
+	}
+	for id, saved := range s.restored {
+		if s.ttl > 0 && now.Sub(saved.Time) > s.ttl {
+			delete(s.restored, id)
+		}
+	}
+
+	// write to a temporary file and rename it not to break the file
+	temp := s.path + ".new"
+	f, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(s.jvms)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(temp, s.path); err != nil {
+		return err
+	}
+
+	logp.Debug(DEBUG_SELECTOR, "Wrote the state of %v JVMs to %v", len(s.jvms), s.path)
+	s.dirty = false
+	s.flushed = now
+	return nil
+}
+
+// JVMID returns the ID of the JVM, which is the pid and the time when the JVM
+// was created, so that a JVM which reuses the pid has another ID. longs are
+// the values of a snapshot. It returns an empty string if
+// sun.rt.createVmBeginTime is not available.
+func JVMID(pid string, longs map[string]int64) string {
+	begin, ok := longs[createVMBeginTime]
+	if !ok {
+		return ""
+	}
+	return pid + "@" + strconv.FormatInt(begin, 10)
+}
+
+// Fingerprint returns a hash of constant counters.
+func Fingerprint(longs map[string]int64, strs map[string]string) string {
+	lines := make([]string, 0, len(longs)+len(strs))
+	for name, value := range longs {
+		lines = append(lines, name+"="+strconv.FormatInt(value, 10))
+	}
+	for name, value := range strs {
+		lines = append(lines, name+"="+strconv.Quote(value))
+	}
+	sort.Strings(lines)
+
+	hash := sha1.New()
+	for _, line := range lines {
+		fmt.Fprintln(hash, line)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// restore restores the previous values of the process from the state store
+// at the first fetch after hsbeat restarts. Constants are removed from the
+// entries if they have been shipped before the restart, except entries in
+// force_collect. It returns true if constants are shipped at this fetch.
+func (m *MetricSet) restore(p *ProcStats, snapshot *Snapshot) bool {
+	if p.previousData != nil {
+		return false
+	}
+	store := m.tracker.Store()
+	saved := store.Restore(snapshot)
+	if saved == nil {
+		return true
+	}
+	logp.Debug(DEBUG_SELECTOR, "Restored the previous values of %v from %v", p.pid, saved.Time)
+	p.previousData = saved.Longs
+	p.previousStrings = saved.Strings
+	p.previousTime = saved.Time
+
+	shipped, exists := store.Shipped(snapshot.ID, m.Name())
+	if !exists || shipped.Fingerprint != Fingerprint(snapshot.Constants, snapshot.ConstantStrings) {
+		return true
+	}
+	forced := make(map[string]bool, len(m.config.ForceCachedEntries))
+	for _, name := range m.config.ForceCachedEntries {
+		forced[strings.Replace(name, ".", "/", -1)] = true
+	}
+	entries := make([]PerfDataEntry, 0, len(snapshot.Entries))
+	for _, entry := range snapshot.Entries {
+		if entry.DataVariability != VariabilityConstant || forced[entry.EntryName] {
+			entries = append(entries, entry)
+		}
+	}
+	snapshot.Entries = entries
+	return false
+}
+
+// save saves the fingerprint of constants if they are shipped at this fetch.
+// Values of the snapshot are saved by the Tracker.
+func (m *MetricSet) save(snapshot *Snapshot, constantsShipped bool) {
+	if constantsShipped {
+		m.tracker.Store().SaveShipped(snapshot.ID, m.Name(), ShippedConstants{
+			Fingerprint: Fingerprint(snapshot.Constants, snapshot.ConstantStrings),
+			Time:        snapshot.Time,
+		})
+	}
+}
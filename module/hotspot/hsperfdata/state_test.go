@@ -0,0 +1,178 @@
+package hsperfdata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	longs := map[string]int64{"sun/os/hrt/frequency": 1000000000}
+	strs := map[string]string{"java/property/java/version": "11.0.2"}
+
+	fingerprint := Fingerprint(longs, strs)
+	assertEquals(t, fingerprint, Fingerprint(longs, strs))
+
+	strs["java/property/java/version"] = "11.0.3"
+	if fingerprint == Fingerprint(longs, strs) {
+		t.Errorf("fingerprint should be changed when constants are changed")
+	}
+}
+
+func TestJVMID(t *testing.T) {
+	assertEquals(t, "123@1500000000000", JVMID("123", newSnapshot([]PerfDataEntry{
+		{EntryName: createVMBeginTime, DataType: 'J', DataVariability: VariabilityVariable, LongValue: 1500000000000},
+	}, time.Now()).Longs))
+	assertEquals(t, "", JVMID("123", nil))
+}
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "hsbeat-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newStore(filepath.Join(dir, "hotspot.state"), 0, time.Hour), func() { os.RemoveAll(dir) }
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	now := time.Now()
+
+	constants := map[string]int64{hrtFrequency: 1000}
+	constantStrings := map[string]string{"sun/rt/javaCommand": "Main"}
+	s.SetStatus("1@100", "1", StatusAttached, now)
+	s.SaveSnapshot(&Snapshot{
+		ID:              "1@100",
+		Time:            now,
+		Longs:           map[string]int64{"a": 1, hrtFrequency: 1000},
+		Strings:         map[string]string{"b": "c", "sun/rt/javaCommand": "Main"},
+		Constants:       constants,
+		ConstantStrings: constantStrings,
+	})
+	s.SaveShipped("1@100", "jvminfo", ShippedConstants{Fingerprint: "f", Time: now})
+	s.SaveSnapshot(&Snapshot{Time: now}) // not identified
+	if err := s.Flush(now); err != nil {
+		t.Fatal(err)
+	}
+
+	// constants are not saved
+	saved := s.jvms["1@100"].Snapshot
+	assertEquals(t, 1, len(saved.Longs))
+	assertEquals(t, 1, len(saved.Strings))
+
+	loaded := newStore(s.path, 0, time.Hour)
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	assertEquals(t, 1, len(loaded.jvms))
+	assertEquals(t, StatusAttached, loaded.jvms["1@100"].Status)
+
+	current := func(id string, at time.Duration) *Snapshot {
+		return &Snapshot{ID: id, Time: now.Add(at), Constants: constants, ConstantStrings: constantStrings}
+	}
+	snapshot := loaded.Restore(current("1@100", time.Minute))
+	assertEquals(t, int64(1), snapshot.Longs["a"])
+	assertEquals(t, int64(1000), snapshot.Longs[hrtFrequency])
+	assertEquals(t, "c", snapshot.Strings["b"])
+	assertEquals(t, "Main", snapshot.Strings["sun/rt/javaCommand"])
+	assertEquals(t, true, snapshot.Time.Equal(now))
+	if loaded.Restore(current("1@200", 0)) != nil {
+		t.Errorf("snapshot of another JVM should not be restored")
+	}
+	if loaded.Restore(current("1@100", 2*time.Hour)) != nil {
+		t.Errorf("snapshot older than TTL should not be restored")
+	}
+
+	// snapshots which are saved after the load are not restored
+	loaded.SaveSnapshot(&Snapshot{ID: "1@100", Time: now.Add(time.Minute), Longs: map[string]int64{"a": 2}})
+	assertEquals(t, int64(1), loaded.Restore(current("1@100", time.Minute)).Longs["a"])
+
+	loaded.SetStatus("1@100", "1", StatusDetached, now.Add(time.Minute))
+	if loaded.Restore(current("1@100", time.Minute)) != nil {
+		t.Errorf("snapshot of a detached JVM should not be restored")
+	}
+
+	shipped, ok := loaded.Shipped("1@100", "jvminfo")
+	assertEquals(t, true, ok)
+	assertEquals(t, "f", shipped.Fingerprint)
+}
+
+func TestStoreFlush(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	s.flushInterval = 10 * time.Second
+	now := time.Now()
+
+	s.SetStatus("1@100", "1", StatusDetached, now)
+	s.Flush(now)
+	assertEquals(t, false, s.dirty)
+
+	s.SetStatus("2@100", "2", StatusAttached, now.Add(time.Second))
+	s.Flush(now.Add(time.Second))
+	assertEquals(t, true, s.dirty) // within the flush interval
+
+	// the detached JVM expires
+	s.Flush(now.Add(2 * time.Hour))
+	assertEquals(t, false, s.dirty)
+	assertEquals(t, 0, len(s.jvms))
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	s.SetStatus("1@100", "1", StatusAttached, time.Now())
+	s.SaveSnapshot(&Snapshot{ID: "1@100", Time: time.Now()})
+	if s.Restore(&Snapshot{ID: "1@100", Time: time.Now()}) != nil {
+		t.Errorf("nil store should not restore snapshots")
+	}
+	if err := s.Flush(time.Now()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRestoreAfterRestart(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+	config := DefaultConfig()
+	config.ForceCachedEntries = []string{"sun.os.hrt.frequency"}
+	m := &MetricSet{config: config, tracker: &Tracker{options: ProcessOptions{Store: s}}}
+	now := time.Now()
+
+	read := func(at time.Duration, count int64) *Snapshot {
+		snapshot := newSnapshot([]PerfDataEntry{
+			{EntryName: hrtFrequency, DataType: 'J', DataVariability: VariabilityConstant, LongValue: 1000},
+			{EntryName: createVMBeginTime, DataType: 'J', DataVariability: VariabilityVariable, LongValue: 100},
+			monotonicEntry("count", count),
+		}, now.Add(at))
+		snapshot.ID = JVMID("1", snapshot.Longs)
+		snapshot.Constants = map[string]int64{hrtFrequency: 1000}
+		return snapshot
+	}
+
+	p := &ProcStats{pid: "1", config: &m.config}
+	snapshot := read(0, 10)
+	s.SaveSnapshot(snapshot) // by the Tracker
+	shipped := m.restore(p, snapshot)
+	assertEquals(t, true, shipped)
+	p.buildMapStr(snapshot)
+	m.save(snapshot, shipped)
+	if err := s.Flush(now); err != nil {
+		t.Fatal(err)
+	}
+
+	// hsbeat restarts
+	s = newStore(s.path, 0, time.Hour)
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	m.tracker.options.Store = s
+	p = &ProcStats{pid: "1", config: &m.config}
+	snapshot = read(time.Second, 30)
+	assertEquals(t, false, m.restore(p, snapshot))
+	event := p.buildMapStr(snapshot)
+	assertEquals(t, int64(20), event["count/diff"])
+	assertEquals(t, int64(1000), event[hrtFrequency]) // force_collect
+	assertEquals(t, int64(100), event[createVMBeginTime])
+}
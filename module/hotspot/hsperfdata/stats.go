@@ -0,0 +1,132 @@
+package hsperfdata
+
+import (
+	"expvar"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Metrics of the collection pipeline of hsbeat itself. They are exposed via
+// expvar and shipped by the collector metricset.
+var (
+	statJVMsAttached      = expvar.NewInt("hotspot.jvms.attached")
+	statJVMsDetached      = expvar.NewInt("hotspot.jvms.detached")
+	statJVMsStale         = expvar.NewInt("hotspot.jvms.stale")
+	statDiscoveries       = expvar.NewInt("hotspot.discovery.count")
+	statDiscoveryDuration = expvar.NewInt("hotspot.discovery.duration_us")
+	statReads             = expvar.NewInt("hotspot.read.count")
+	statBytesRead         = expvar.NewInt("hotspot.read.bytes")
+	statTornReadRetries   = expvar.NewInt("hotspot.read.torn_retries")
+	statPermissionErrors  = expvar.NewInt("hotspot.read.permission_errors")
+	statParseDuration     = expvar.NewMap("hotspot.parse.duration_us") // PID to the duration of the last read
+)
+
+// attachedJVMs counts trackers which attach each JVM, so that a JVM which is
+// attached by several metricsets is counted once.
+var (
+	attachedMu   sync.Mutex
+	attachedJVMs = make(map[string]int)
+)
+
+func countAttach(pid string) {
+	attachedMu.Lock()
+	defer attachedMu.Unlock()
+	attachedJVMs[pid]++
+	statJVMsAttached.Set(int64(len(attachedJVMs)))
+}
+
+func countDetach(pid string) {
+	attachedMu.Lock()
+	defer attachedMu.Unlock()
+	if attachedJVMs[pid]--; attachedJVMs[pid] > 0 {
+		return
+	}
+	delete(attachedJVMs, pid)
+	statJVMsAttached.Set(int64(len(attachedJVMs)))
+	statJVMsDetached.Add(1)
+	statParseDuration.Delete(pid)
+}
+
+// staleJVMs are stale pids which are found by the last discovery of each
+// tracker, so that a pid which is found by several trackers is counted once.
+var (
+	staleMu   sync.Mutex
+	staleJVMs = make(map[*Tracker]map[string]bool)
+)
+
+func countStale(t *Tracker, pids map[string]bool) {
+	staleMu.Lock()
+	defer staleMu.Unlock()
+	staleJVMs[t] = pids
+	stale := make(map[string]bool)
+	for _, tracked := range staleJVMs {
+		for pid := range tracked {
+			stale[pid] = true
+		}
+	}
+	statJVMsStale.Set(int64(len(stale)))
+}
+
+func countParse(pid string, d time.Duration) {
+	v := new(expvar.Int)
+	v.Set(int64(d / time.Microsecond))
+	statParseDuration.Set(pid, v)
+}
+
+// ProcessAlive returns false if procfs is available and the process does
+// not exist. It returns true if it cannot be checked.
+func ProcessAlive(pid string) bool {
+	if _, err := os.Stat("/proc/self"); err != nil {
+		return true
+	}
+	_, err := os.Stat(filepath.Join("/proc", pid))
+	return err == nil
+}
+
+func micros(v int64) float64 {
+	return float64(v) / 1000
+}
+
+// CollectorStats returns the metrics of the collection pipeline. Parse
+// durations are the max and the average of the last read of each JVM.
+func CollectorStats() common.MapStr {
+	var max, sum int64
+	var jvms int
+	statParseDuration.Do(func(kv expvar.KeyValue) {
+		d := kv.Value.(*expvar.Int).Value()
+		if d > max {
+			max = d
+		}
+		sum += d
+		jvms++
+	})
+	parse := common.MapStr{"max": common.MapStr{"ms": micros(max)}}
+	if jvms > 0 {
+		parse["avg"] = common.MapStr{"ms": micros(sum) / float64(jvms)}
+	}
+
+	return common.MapStr{
+		"jvms": common.MapStr{
+			"attached": statJVMsAttached.Value(),
+			"detached": statJVMsDetached.Value(),
+			"stale":    statJVMsStale.Value(),
+		},
+		"discovery": common.MapStr{
+			"count":    statDiscoveries.Value(),
+			"duration": common.MapStr{"ms": micros(statDiscoveryDuration.Value())},
+		},
+		"read": common.MapStr{
+			"count":             statReads.Value(),
+			"bytes":             statBytesRead.Value(),
+			"torn_retries":      statTornReadRetries.Value(),
+			"permission_errors": statPermissionErrors.Value(),
+		},
+		"parse": common.MapStr{
+			"duration": parse,
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package hsperfdata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountAttachedJVMs(t *testing.T) {
+	detached := statJVMsDetached.Value()
+
+	// the JVM is attached by two metricsets
+	countAttach("stats-1")
+	countAttach("stats-1")
+	countAttach("stats-2")
+	countParse("stats-1", 2*time.Millisecond)
+	countParse("stats-2", 4*time.Millisecond)
+	assertEquals(t, int64(2), statJVMsAttached.Value())
+
+	stats := CollectorStats()
+	assertEquals(t, int64(2), getValue(t, stats, "jvms.attached"))
+	assertEquals(t, 4.0, getValue(t, stats, "parse.duration.max.ms"))
+	assertEquals(t, 3.0, getValue(t, stats, "parse.duration.avg.ms"))
+
+	countDetach("stats-1")
+	assertEquals(t, int64(2), statJVMsAttached.Value())
+	assertEquals(t, detached, statJVMsDetached.Value())
+
+	countDetach("stats-1")
+	countDetach("stats-2")
+	assertEquals(t, int64(0), statJVMsAttached.Value())
+	assertEquals(t, detached+2, statJVMsDetached.Value())
+	assertEquals(t, nil, statParseDuration.Get("stats-1"))
+
+	stats = CollectorStats()
+	assertEquals(t, 0.0, getValue(t, stats, "parse.duration.max.ms"))
+}
+
+func TestCountStaleJVMs(t *testing.T) {
+	a, b := &Tracker{}, &Tracker{}
+	defer func() {
+		countStale(a, nil)
+		countStale(b, nil)
+	}()
+
+	countStale(a, map[string]bool{"1": true, "2": true})
+	countStale(b, map[string]bool{"2": true, "3": true})
+	assertEquals(t, int64(3), statJVMsStale.Value())
+
+	countStale(a, map[string]bool{"1": true, "2": true}) // the next discovery
+	assertEquals(t, int64(3), statJVMsStale.Value())
+
+	countStale(b, nil)
+	assertEquals(t, int64(2), statJVMsStale.Value())
+}
+
+func TestCollectorStats(t *testing.T) {
+	countParse("stats-3", 1500*time.Microsecond)
+	countParse("stats-4", 500*time.Microsecond)
+	defer statParseDuration.Delete("stats-3")
+	defer statParseDuration.Delete("stats-4")
+
+	stats := CollectorStats()
+	for _, key := range []string{
+		"jvms.attached", "jvms.detached", "jvms.stale",
+		"discovery.count", "read.count", "read.bytes", "read.torn_retries", "read.permission_errors",
+	} {
+		if _, ok := getValue(t, stats, key).(int64); !ok {
+			t.Errorf("%v should be int64", key)
+		}
+	}
+	if _, ok := getValue(t, stats, "discovery.duration.ms").(float64); !ok {
+		t.Errorf("discovery.duration.ms should be float64")
+	}
+	assertEquals(t, 1.5, getValue(t, stats, "parse.duration.max.ms"))
+	assertEquals(t, 1.0, getValue(t, stats, "parse.duration.avg.ms"))
+}
+
+func TestProcessAlive(t *testing.T) {
+	assertEquals(t, true, ProcessAlive("self"))
+}
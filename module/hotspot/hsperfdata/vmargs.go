@@ -0,0 +1,227 @@
+package hsperfdata
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// GCEpsilon is the no-op collector which is selected by -XX:+UseEpsilonGC.
+const GCEpsilon = "epsilon"
+
+// gcFlags maps flags which select the garbage collector to the collector.
+var gcFlags = map[string]string{
+	"UseSerialGC":        GCSerial,
+	"UseParallelGC":      GCParallel,
+	"UseParallelOldGC":   GCParallel,
+	"UseConcMarkSweepGC": GCCMS,
+	"UseG1GC":            GCG1,
+	"UseZGC":             GCZ,
+	"UseShenandoahGC":    GCShenandoah,
+	"UseEpsilonGC":       GCEpsilon,
+}
+
+// sizeFlags maps -XX flags of sizes to fields of VMArgs.
+var sizeFlags = map[string]func(a *VMArgs) *int64{
+	"InitialHeapSize": func(a *VMArgs) *int64 { return &a.HeapInitial },
+	"MaxHeapSize":     func(a *VMArgs) *int64 { return &a.HeapMax },
+	"NewSize":         func(a *VMArgs) *int64 { return &a.YoungSize },
+	"ThreadStackSize": func(a *VMArgs) *int64 { return &a.StackSize },
+}
+
+// Agent is a Java agent or a JVMTI agent.
+type Agent struct {
+	Type    string // javaagent, agentlib or agentpath
+	Name    string // path of the jar, the library name or the path
+	Options string
+}
+
+// VMArgs is the result of parsing java.rt.vmArgs and java.rt.vmFlags.
+// Sizes are zero if they are not specified.
+type VMArgs struct {
+	HeapInitial int64
+	HeapMax     int64
+	YoungSize   int64
+	StackSize   int64
+	GC          string
+	Flags       map[string]interface{} // bool for +/- flags, string for settings
+	Properties  map[string]string
+	Agents      []Agent
+	Others      []string
+}
+
+// ParseSize parses a size such as 512m or 2G in bytes.
+func ParseSize(s string) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	multiplier := int64(1)
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		multiplier = 1 << 10
+	case 'm', 'M':
+		multiplier = 1 << 20
+	case 'g', 'G':
+		multiplier = 1 << 30
+	case 't', 'T':
+		multiplier = 1 << 40
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return v * multiplier, true
+}
+
+// ParseVMArgs parses JVM flags (java.rt.vmFlags) and JVM arguments
+// (java.rt.vmArgs). Flags are applied before arguments, and the last one
+// wins if the same setting is specified more than once like HotSpot does.
+func ParseVMArgs(vmFlags, vmArgs string) *VMArgs {
+	a := &VMArgs{
+		Flags:      make(map[string]interface{}),
+		Properties: make(map[string]string),
+	}
+	for _, flag := range strings.Fields(vmFlags) {
+		a.setFlag(flag)
+	}
+	for _, arg := range strings.Fields(vmArgs) {
+		a.parseArg(arg)
+	}
+	return a
+}
+
+func (a *VMArgs) parseArg(arg string) {
+	switch {
+	case strings.HasPrefix(arg, "-XX:"):
+		a.setFlag(arg[len("-XX:"):])
+	case strings.HasPrefix(arg, "-Xms"):
+		a.setSize(&a.HeapInitial, arg[len("-Xms"):], arg)
+	case strings.HasPrefix(arg, "-Xmx"):
+		a.setSize(&a.HeapMax, arg[len("-Xmx"):], arg)
+	case strings.HasPrefix(arg, "-Xmn"):
+		a.setSize(&a.YoungSize, arg[len("-Xmn"):], arg)
+	case strings.HasPrefix(arg, "-Xss"):
+		a.setSize(&a.StackSize, arg[len("-Xss"):], arg)
+	case strings.HasPrefix(arg, "-D"):
+		name, value := splitOption(arg[len("-D"):], "=")
+		a.Properties[name] = value
+	case strings.HasPrefix(arg, "-javaagent:"):
+		a.addAgent("javaagent", arg[len("-javaagent:"):])
+	case strings.HasPrefix(arg, "-agentlib:"):
+		a.addAgent("agentlib", arg[len("-agentlib:"):])
+	case strings.HasPrefix(arg, "-agentpath:"):
+		a.addAgent("agentpath", arg[len("-agentpath:"):])
+	default:
+		a.Others = append(a.Others, arg)
+	}
+}
+
+// setFlag applies a flag in the form of +Flag, -Flag or Flag=value.
+func (a *VMArgs) setFlag(flag string) {
+	if flag == "" {
+		return
+	}
+
+	switch flag[0] {
+	case '+', '-':
+		name := flag[1:]
+		enabled := flag[0] == '+'
+		a.Flags[name] = enabled
+		if gc, ok := gcFlags[name]; ok {
+			if enabled {
+				a.GC = gc
+			} else if a.GC == gc {
+				a.GC = ""
+			}
+		}
+	default:
+		name, value := splitOption(flag, "=")
+		a.Flags[name] = value
+		if field, ok := sizeFlags[name]; ok {
+			if size, ok := ParseSize(value); ok {
+				*field(a) = size
+			}
+		}
+	}
+}
+
+func (a *VMArgs) setSize(field *int64, value, arg string) {
+	if size, ok := ParseSize(value); ok {
+		*field = size
+	} else {
+		a.Others = append(a.Others, arg)
+	}
+}
+
+func (a *VMArgs) addAgent(agentType, spec string) {
+	name, options := splitOption(spec, "=")
+	a.Agents = append(a.Agents, Agent{Type: agentType, Name: name, Options: options})
+}
+
+func splitOption(s, sep string) (string, string) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):]
+	}
+	return s, ""
+}
+
+// ToMapStr returns the result as event fields. Dots in names of system
+// properties are converted to '/' in the same way as counter names.
+func (a *VMArgs) ToMapStr() common.MapStr {
+	result := common.MapStr{}
+
+	heap := common.MapStr{}
+	for field, size := range map[string]int64{
+		"initial": a.HeapInitial,
+		"max":     a.HeapMax,
+		"young":   a.YoungSize,
+	} {
+		if size > 0 {
+			heap[field] = common.MapStr{"bytes": size}
+		}
+	}
+	if len(heap) > 0 {
+		result["heap"] = heap
+	}
+	if a.StackSize > 0 {
+		result["thread_stack"] = common.MapStr{"bytes": a.StackSize}
+	}
+	if a.GC != "" {
+		result["gc"] = a.GC
+	}
+
+	if len(a.Flags) > 0 {
+		flags := common.MapStr{}
+		for name, value := range a.Flags {
+			flags[name] = value
+		}
+		result["flags"] = flags
+	}
+	if len(a.Properties) > 0 {
+		properties := common.MapStr{}
+		for name, value := range a.Properties {
+			properties[strings.Replace(name, ".", "/", -1)] = value
+		}
+		result["properties"] = properties
+	}
+	if len(a.Agents) > 0 {
+		agents := make([]common.MapStr, 0, len(a.Agents))
+		for _, agent := range a.Agents {
+			m := common.MapStr{"type": agent.Type, "name": agent.Name}
+			if agent.Options != "" {
+				m["options"] = agent.Options
+			}
+			agents = append(agents, m)
+		}
+		result["agents"] = agents
+	}
+	if len(a.Others) > 0 {
+		result["others"] = a.Others
+	}
+
+	return result
+}
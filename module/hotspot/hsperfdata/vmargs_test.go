@@ -0,0 +1,75 @@
+package hsperfdata
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"1024": 1024,
+		"64k":  64 << 10,
+		"512m": 512 << 20,
+		"2G":   2 << 30,
+		"1t":   1 << 40,
+	} {
+		size, ok := ParseSize(s)
+		assertEquals(t, true, ok)
+		assertEquals(t, expected, size)
+	}
+
+	for _, s := range []string{"", "m", "1x", "-1g"} {
+		if _, ok := ParseSize(s); ok {
+			t.Errorf("%v should not be parsed", s)
+		}
+	}
+}
+
+func TestParseVMArgs(t *testing.T) {
+	a := ParseVMArgs("", "-Xms256m -Xmx1g -Xmn128m -Xss512k -XX:+UseG1GC -XX:-UseCompressedOops "+
+		"-XX:MaxMetaspaceSize=256m -Dapp.name=shop -Dflag -javaagent:/opt/agent.jar=port=9000 "+
+		"-agentlib:jdwp=transport=dt_socket -verbose:gc")
+
+	assertEquals(t, int64(256<<20), a.HeapInitial)
+	assertEquals(t, int64(1<<30), a.HeapMax)
+	assertEquals(t, int64(128<<20), a.YoungSize)
+	assertEquals(t, int64(512<<10), a.StackSize)
+	assertEquals(t, GCG1, a.GC)
+	assertEquals(t, true, a.Flags["UseG1GC"])
+	assertEquals(t, false, a.Flags["UseCompressedOops"])
+	assertEquals(t, "256m", a.Flags["MaxMetaspaceSize"])
+	assertEquals(t, "shop", a.Properties["app.name"])
+	assertEquals(t, "", a.Properties["flag"])
+	assertEquals(t, 2, len(a.Agents))
+	assertEquals(t, Agent{"javaagent", "/opt/agent.jar", "port=9000"}, a.Agents[0])
+	assertEquals(t, Agent{"agentlib", "jdwp", "transport=dt_socket"}, a.Agents[1])
+	assertEquals(t, 1, len(a.Others))
+	assertEquals(t, "-verbose:gc", a.Others[0])
+}
+
+func TestParseVMArgsLastOneWins(t *testing.T) {
+	a := ParseVMArgs("+UseSerialGC MaxHeapSize=64m",
+		"-Xmx512m -XX:MaxHeapSize=2g -XX:+UseConcMarkSweepGC -XX:+UseParallelGC -Dx=1 -Dx=2")
+
+	assertEquals(t, int64(2<<30), a.HeapMax)
+	assertEquals(t, GCParallel, a.GC)
+	assertEquals(t, "2", a.Properties["x"])
+}
+
+func TestParseVMArgsDisableGC(t *testing.T) {
+	a := ParseVMArgs("", "-XX:+UseG1GC -XX:-UseG1GC")
+	assertEquals(t, "", a.GC)
+
+	event := a.ToMapStr()
+	if _, exists := event["gc"]; exists {
+		t.Errorf("gc should not be shipped if no collector is selected")
+	}
+}
+
+func TestVMArgsToMapStr(t *testing.T) {
+	event := ParseVMArgs("", "-Xmx1g -XX:+UseG1GC -Dapp.name=shop").ToMapStr()
+
+	assertEquals(t, int64(1<<30), getValue(t, event, "heap.max.bytes"))
+	assertEquals(t, GCG1, event["gc"])
+	assertEquals(t, true, getValue(t, event, "flags.UseG1GC"))
+	assertEquals(t, "shop", getValue(t, event, "properties.app/name"))
+}
@@ -0,0 +1,251 @@
+package jvminfo
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// Reasons why the inventory is shipped.
+const (
+	ReasonAttach    = "attach"
+	ReasonChanged   = "changed"
+	ReasonScheduled = "scheduled"
+)
+
+const (
+	javaProperty = "java/property/"
+	sunProperty  = "sun/property/"
+)
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"sun/rt/createVmBeginTime"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "jvminfo", New); err != nil {
+		panic(err)
+	}
+}
+
+// shipped is the inventory which is shipped last time.
+type shipped struct {
+	fingerprint string
+	time        time.Time
+}
+
+// MetricSet ships an inventory document of constant counters of each JVM
+// when it is attached, when constants are changed and every
+// jvminfo.interval.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler  *hsperfdata.Sampler
+	store    *hsperfdata.Store
+	interval time.Duration
+	shipped  map[string]shipped // JVM ID (or PID) to the last shipped inventory
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	sampler := hsperfdata.NewSampler(base, &config, prefixes)
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       sampler,
+		store:         sampler.Store(),
+		interval:      config.JVMInfoInterval,
+		shipped:       make(map[string]shipped),
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns inventory
+// documents which should be shipped at this time. Errors are returned only
+// if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	sampled := make(map[string]bool)
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		sampled[jvmKey(pid, current)] = true
+		if event := m.inventoryEvent(pid, current); event != nil {
+			events = append(events, event)
+		}
+	})
+	for key := range m.shipped {
+		if !sampled[key] {
+			delete(m.shipped, key)
+		}
+	}
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch JVM info for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+// jvmKey returns the JVM ID of the snapshot, so a JVM which is re-created
+// with the same PID is another JVM. It is the PID if the JVM cannot be
+// identified.
+func jvmKey(pid string, current *hsperfdata.Snapshot) string {
+	if current.ID == "" {
+		return pid
+	}
+	return current.ID
+}
+
+// inventoryEvent returns the inventory of the JVM if it should be shipped at
+// this time, or nil.
+func (m *MetricSet) inventoryEvent(pid string, current *hsperfdata.Snapshot) common.MapStr {
+	key := jvmKey(pid, current)
+	fingerprint := hsperfdata.Fingerprint(current.Constants, current.ConstantStrings)
+	last, exists := m.shipped[key]
+	if !exists {
+		// the inventory which is shipped before hsbeat restarts
+		if s, ok := m.store.Shipped(current.ID, m.Name()); ok {
+			last, exists = shipped{fingerprint: s.Fingerprint, time: s.Time}, true
+		}
+	}
+	reason := shipReason(exists, last, fingerprint, current.Time, m.interval)
+	if reason == "" {
+		return nil
+	}
+
+	event := inventory(pid, current)
+	event["fingerprint"] = fingerprint
+	event["reason"] = reason
+	current.AddLabels(event)
+	m.shipped[key] = shipped{fingerprint: fingerprint, time: current.Time}
+	m.store.SaveShipped(current.ID, m.Name(), hsperfdata.ShippedConstants{
+		Fingerprint: fingerprint,
+		Time:        current.Time,
+	})
+	return event
+}
+
+// shipReason returns why the inventory should be shipped, or an empty
+// string if it should not be shipped.
+func shipReason(exists bool, last shipped, fingerprint string, now time.Time, interval time.Duration) string {
+	switch {
+	case !exists:
+		return ReasonAttach
+	case last.fingerprint != fingerprint:
+		return ReasonChanged
+	case interval > 0 && now.Sub(last.time) >= interval:
+		return ReasonScheduled
+	}
+	return ""
+}
+
+// MajorVersion returns the major version of Java from java.version, e.g. 8
+// for "1.8.0_292" and 11 for "11.0.2". It returns false if the version
+// cannot be parsed.
+func MajorVersion(version string) (int, bool) {
+	version = strings.TrimPrefix(version, "1.")
+	end := 0
+	for end < len(version) && version[end] >= '0' && version[end] <= '9' {
+		end++
+	}
+	major, err := strconv.Atoi(version[:end])
+	if err != nil {
+		return 0, false
+	}
+	return major, true
+}
+
+// inventory builds the document from constant counters and the start time
+// of the JVM, which is a variable counter. System properties are shipped
+// with names in the same form as the hsperfdata metricset.
+func inventory(pid string, snapshot *hsperfdata.Snapshot) common.MapStr {
+	longs, strs := snapshot.Constants, snapshot.ConstantStrings
+	event := common.MapStr{"pid": pid}
+
+	java := common.MapStr{}
+	vm := common.MapStr{}
+	for field, property := range map[string]string{
+		"version": "java.version",
+		"vendor":  "java.vendor",
+		"home":    "java.home",
+	} {
+		if v, ok := strs[javaProperty+strings.Replace(property, ".", "/", -1)]; ok {
+			java[field] = v
+		}
+	}
+	for field, property := range map[string]string{
+		"name":                  "java.vm.name",
+		"version":               "java.vm.version",
+		"vendor":                "java.vm.vendor",
+		"info":                  "java.vm.info",
+		"specification_version": "java.vm.specification.version",
+	} {
+		if v, ok := strs[javaProperty+strings.Replace(property, ".", "/", -1)]; ok {
+			vm[field] = v
+		}
+	}
+	if version, ok := java["version"].(string); ok {
+		if major, ok := MajorVersion(version); ok {
+			java["major_version"] = major
+		}
+	}
+	if len(vm) > 0 {
+		java["vm"] = vm
+	}
+	if len(java) > 0 {
+		event["java"] = java
+	}
+
+	for field, name := range map[string]string{
+		"command":  "sun/rt/javaCommand",
+		"vm_args":  "java/rt/vmArgs",
+		"vm_flags": "java/rt/vmFlags",
+	} {
+		if v, ok := strs[name]; ok {
+			event[field] = v
+		}
+	}
+	vmFlags, hasFlags := strs["java/rt/vmFlags"]
+	vmArgs, hasArgs := strs["java/rt/vmArgs"]
+	if hasFlags || hasArgs {
+		event["vm_options"] = hsperfdata.ParseVMArgs(vmFlags, vmArgs).ToMapStr()
+	}
+	if v, ok := snapshot.Longs["sun/rt/createVmBeginTime"]; ok && v > 0 {
+		event["start_time"] = common.Time(time.Unix(0, v*int64(time.Millisecond)))
+	}
+	if v, ok := longs["sun/os/hrt/frequency"]; ok {
+		event["hrt_frequency"] = v
+	}
+
+	properties := common.MapStr{}
+	constants := common.MapStr{}
+	for name, value := range strs {
+		if strings.HasPrefix(name, javaProperty) || strings.HasPrefix(name, sunProperty) {
+			properties[name] = value
+		} else {
+			constants[name] = value
+		}
+	}
+	for name, value := range longs {
+		constants[name] = value
+	}
+	if len(properties) > 0 {
+		event["properties"] = properties
+	}
+	if len(constants) > 0 {
+		event["constants"] = constants
+	}
+
+	return event
+}
@@ -0,0 +1,107 @@
+package jvminfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func TestMajorVersion(t *testing.T) {
+	for version, expected := range map[string]int{
+		"1.8.0_292": 8,
+		"11.0.2":    11,
+		"17":        17,
+		"9-ea":      9,
+	} {
+		major, ok := MajorVersion(version)
+		hsperftest.AssertEquals(t, true, ok)
+		hsperftest.AssertEquals(t, expected, major)
+	}
+
+	_, ok := MajorVersion("unknown")
+	hsperftest.AssertEquals(t, false, ok)
+}
+
+func TestShipReason(t *testing.T) {
+	now := time.Now()
+	last := shipped{fingerprint: "a", time: now.Add(-time.Minute)}
+
+	hsperftest.AssertEquals(t, ReasonAttach, shipReason(false, shipped{}, "a", now, time.Hour))
+	hsperftest.AssertEquals(t, ReasonChanged, shipReason(true, last, "b", now, time.Hour))
+	hsperftest.AssertEquals(t, "", shipReason(true, last, "a", now, time.Hour))
+	hsperftest.AssertEquals(t, ReasonScheduled, shipReason(true, last, "a", now, time.Minute))
+	hsperftest.AssertEquals(t, "", shipReason(true, last, "a", now, 0))
+}
+
+func TestInventory(t *testing.T) {
+	longs := map[string]int64{
+		"sun/os/hrt/frequency": 1000000000,
+	}
+	strs := map[string]string{
+		"java/property/java/version":    "1.8.0_292",
+		"java/property/java/vm/name":    "OpenJDK 64-Bit Server VM",
+		"java/property/java/vm/version": "25.292-b10",
+		"sun/rt/javaCommand":            "org.example.Main --port 8080",
+		"java/rt/vmArgs":                "-Xmx1g -XX:+UseConcMarkSweepGC",
+		"sun/gc/collector/0/name":       "PSScavenge",
+	}
+
+	event := inventory("1", &hsperfdata.Snapshot{
+		Longs:           map[string]int64{"sun/rt/createVmBeginTime": 1500000000000},
+		Constants:       longs,
+		ConstantStrings: strs,
+	})
+	hsperftest.AssertEquals(t, "1", event["pid"])
+	hsperftest.AssertEquals(t, "1.8.0_292", hsperftest.GetValue(t, event, "java.version"))
+	hsperftest.AssertEquals(t, 8, hsperftest.GetValue(t, event, "java.major_version"))
+	hsperftest.AssertEquals(t, "OpenJDK 64-Bit Server VM", hsperftest.GetValue(t, event, "java.vm.name"))
+	hsperftest.AssertEquals(t, "org.example.Main --port 8080", event["command"])
+	hsperftest.AssertEquals(t, "-Xmx1g -XX:+UseConcMarkSweepGC", event["vm_args"])
+	hsperftest.AssertEquals(t, int64(1<<30), hsperftest.GetValue(t, event, "vm_options.heap.max.bytes"))
+	hsperftest.AssertEquals(t, hsperfdata.GCCMS, hsperftest.GetValue(t, event, "vm_options.gc"))
+	hsperftest.AssertEquals(t, int64(1000000000), event["hrt_frequency"])
+	hsperftest.AssertEquals(t, common.Time(time.Unix(1500000000, 0)), event["start_time"])
+	hsperftest.AssertEquals(t, "1.8.0_292", event["properties"].(common.MapStr)["java/property/java/version"])
+	hsperftest.AssertEquals(t, "PSScavenge", event["constants"].(common.MapStr)["sun/gc/collector/0/name"])
+}
+
+func TestInventoryFromFile(t *testing.T) {
+	s := hsperftest.Read(t, hsperftest.ReadListing(t, "jdk17-g1.txt"))
+
+	event := inventory("1", s)
+	hsperftest.AssertEquals(t, "17.0.9", hsperftest.GetValue(t, event, "java.version"))
+	hsperftest.AssertEquals(t, 17, hsperftest.GetValue(t, event, "java.major_version"))
+	hsperftest.AssertEquals(t, "app.jar --server.port=8080", event["command"])
+	hsperftest.AssertEquals(t, int64(1<<30), hsperftest.GetValue(t, event, "vm_options.heap.max.bytes"))
+	hsperftest.AssertEquals(t, hsperfdata.GCG1, hsperftest.GetValue(t, event, "vm_options.gc"))
+	hsperftest.AssertEquals(t, int64(1000000000), event["hrt_frequency"])
+	hsperftest.AssertEquals(t, common.Time(time.Unix(1697700000, 123000000)), event["start_time"])
+}
+
+func TestReasonOfRecreatedJVM(t *testing.T) {
+	m := &MetricSet{interval: time.Hour, shipped: make(map[string]shipped)}
+	now := time.Now()
+	snapshot := func(id, version string, at time.Duration) *hsperfdata.Snapshot {
+		return &hsperfdata.Snapshot{
+			ID:              id,
+			Time:            now.Add(at),
+			Longs:           map[string]int64{"sun/rt/createVmBeginTime": 1500000000000},
+			ConstantStrings: map[string]string{"java/property/java/version": version},
+		}
+	}
+
+	hsperftest.AssertEquals(t, ReasonAttach, m.inventoryEvent("1", snapshot("1@100", "11.0.2", 0))["reason"])
+	if event := m.inventoryEvent("1", snapshot("1@100", "11.0.2", time.Second)); event != nil {
+		t.Errorf("the inventory should not be shipped again: %v", event)
+	}
+
+	// the JVM is re-created with the same PID and another version
+	hsperftest.AssertEquals(t, ReasonAttach, m.inventoryEvent("1", snapshot("1@200", "17.0.9", 2*time.Second))["reason"])
+
+	// constants of the same JVM are changed
+	hsperftest.AssertEquals(t, ReasonChanged, m.inventoryEvent("1", snapshot("1@200", "17.0.10", 3*time.Second))["reason"])
+}
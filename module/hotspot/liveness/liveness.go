@@ -0,0 +1,173 @@
+package liveness
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// Kinds of events.
+const (
+	KindUnresponsive = "jvm_unresponsive"
+	KindRecovered    = "jvm_recovered"
+)
+
+const (
+	hrtTicks      = "sun/os/hrt/ticks"
+	safepoints    = "sun/rt/safepoints"
+	safepointTime = "sun/rt/safepointTime"
+	syncTime      = "sun/rt/safepointSyncTime"
+	gcCause       = "sun/gc/cause"
+	noGC          = "No GC"
+)
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{gcCause, "sun/rt/safepoint"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "liveness", New); err != nil {
+		panic(err)
+	}
+}
+
+// watch is the liveness state of a Java process.
+type watch struct {
+	ticks        int64
+	modTimeStamp int64
+	advanced     time.Time // when the counters advanced last time
+	stalled      bool
+}
+
+// MetricSet ships an event when the hsperfdata file of a Java process stops
+// advancing for unresponsive.threshold, and when it advances again.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler   *hsperfdata.Sampler
+	threshold time.Duration
+	watches   map[string]*watch // PID to the liveness state
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+		threshold:     config.UnresponsiveThreshold,
+		watches:       make(map[string]*watch),
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns events of
+// processes which became unresponsive or recovered. Errors are returned only
+// if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	sampled := make(map[string]bool)
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		sampled[pid] = true
+		w, exists := m.watches[pid]
+		if !exists {
+			m.watches[pid] = newWatch(current)
+			return
+		}
+
+		if event := w.update(pid, current, m.threshold, hsperfdata.ProcessAlive(pid)); event != nil {
+			current.AddLabels(event)
+			events = append(events, event)
+		}
+	})
+	for pid := range m.watches {
+		if !sampled[pid] {
+			delete(m.watches, pid)
+		}
+	}
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not check liveness of all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+func newWatch(s *hsperfdata.Snapshot) *watch {
+	return &watch{
+		ticks:        s.Longs[hrtTicks],
+		modTimeStamp: s.ModTimeStamp,
+		advanced:     s.Time,
+	}
+}
+
+// update checks whether sun.os.hrt.ticks or ModTimeStamp in the prologue
+// advanced, and returns an event if the process became unresponsive or
+// recovered. The process is not regarded as unresponsive if it is not
+// alive, because the file of a killed JVM is left as is.
+func (w *watch) update(pid string, s *hsperfdata.Snapshot, threshold time.Duration, alive bool) common.MapStr {
+	ticks := s.Longs[hrtTicks]
+	if ticks != w.ticks || s.ModTimeStamp != w.modTimeStamp {
+		var event common.MapStr
+		if w.stalled {
+			event = stallEvent(pid, KindRecovered, s, w.advanced)
+			logp.Info("Java process %v recovered after %v", pid, s.Time.Sub(w.advanced))
+		}
+		*w = watch{ticks: ticks, modTimeStamp: s.ModTimeStamp, advanced: s.Time}
+		return event
+	}
+
+	if w.stalled || threshold <= 0 || !alive || s.Time.Sub(w.advanced) < threshold {
+		return nil
+	}
+	w.stalled = true
+	logp.Warn("Java process %v is unresponsive: hsperfdata is not updated for %v", pid, s.Time.Sub(w.advanced))
+	return stallEvent(pid, KindUnresponsive, s, w.advanced)
+}
+
+// stallEvent builds the event with the stall duration and the last known
+// safepoint and GC state.
+func stallEvent(pid, kind string, s *hsperfdata.Snapshot, advanced time.Time) common.MapStr {
+	event := common.MapStr{
+		"pid":  pid,
+		"kind": kind,
+		"stall": common.MapStr{
+			"ms":    float64(s.Time.Sub(advanced)) / float64(time.Millisecond),
+			"since": common.Time(advanced),
+		},
+		"hrt_ticks":      s.Longs[hrtTicks],
+		"mod_time_stamp": s.ModTimeStamp,
+	}
+
+	safepoint := common.MapStr{}
+	if v, ok := s.Longs[safepoints]; ok {
+		safepoint["total"] = v
+	}
+	if ms, ok := hsperfdata.TicksToMillis(s.Longs[safepointTime], s.Longs); ok {
+		safepoint["time"] = common.MapStr{"total": common.MapStr{"ms": ms}}
+	}
+	if ms, ok := hsperfdata.TicksToMillis(s.Longs[syncTime], s.Longs); ok {
+		safepoint["sync"] = common.MapStr{"total": common.MapStr{"ms": ms}}
+	}
+	if len(safepoint) > 0 {
+		event["safepoint"] = safepoint
+	}
+
+	if cause, ok := s.Strings[gcCause]; ok {
+		event["gc"] = common.MapStr{
+			"cause":       cause,
+			"in_progress": cause != noGC,
+		}
+	}
+	return event
+}
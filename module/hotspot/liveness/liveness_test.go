@@ -0,0 +1,102 @@
+package liveness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func newSnapshot(now time.Time, ticks, modTimeStamp int64) *hsperfdata.Snapshot {
+	s := hsperftest.NewSnapshot(now, ticks, hsperftest.Values{
+		"sun/rt/safepoints":        10,
+		"sun/rt/safepointTime":     50,
+		"sun/rt/safepointSyncTime": 5,
+		"sun/gc/cause":             "Allocation Failure",
+	})
+	s.ModTimeStamp = modTimeStamp
+	return s
+}
+
+func TestNoEventWhileAdvancing(t *testing.T) {
+	now := time.Now()
+	w := newWatch(newSnapshot(now, 1000, 1000))
+
+	for i := int64(1); i <= 10; i++ {
+		s := newSnapshot(now.Add(time.Duration(i)*time.Second), 1000+i*1000, 1000+i*1000)
+		if event := w.update("1", s, 5*time.Second, true); event != nil {
+			t.Errorf("no event is expected, but %v", event)
+		}
+	}
+}
+
+func TestUnresponsiveAndRecovered(t *testing.T) {
+	now := time.Now()
+	w := newWatch(newSnapshot(now, 1000, 1000))
+
+	if event := w.update("1", newSnapshot(now.Add(4*time.Second), 1000, 1000), 5*time.Second, true); event != nil {
+		t.Errorf("no event is expected before the threshold, but %v", event)
+	}
+
+	event := w.update("1", newSnapshot(now.Add(5*time.Second), 1000, 1000), 5*time.Second, true)
+	if event == nil {
+		t.Fatalf("unresponsive event is expected")
+	}
+	hsperftest.AssertEquals(t, KindUnresponsive, event["kind"])
+	hsperftest.AssertEquals(t, 5000.0, hsperftest.GetValue(t, event, "stall.ms"))
+	hsperftest.AssertEquals(t, common.Time(now), hsperftest.GetValue(t, event, "stall.since"))
+	hsperftest.AssertEquals(t, int64(10), hsperftest.GetValue(t, event, "safepoint.total"))
+	hsperftest.AssertEquals(t, 50.0, hsperftest.GetValue(t, event, "safepoint.time.total.ms"))
+	hsperftest.AssertEquals(t, true, hsperftest.GetValue(t, event, "gc.in_progress"))
+
+	if event := w.update("1", newSnapshot(now.Add(6*time.Second), 1000, 1000), 5*time.Second, true); event != nil {
+		t.Errorf("unresponsive event should be shipped only once, but %v", event)
+	}
+
+	event = w.update("1", newSnapshot(now.Add(8*time.Second), 1000, 9000), 5*time.Second, true)
+	if event == nil {
+		t.Fatalf("recovered event is expected")
+	}
+	hsperftest.AssertEquals(t, KindRecovered, event["kind"])
+	hsperftest.AssertEquals(t, 8000.0, hsperftest.GetValue(t, event, "stall.ms"))
+	hsperftest.AssertEquals(t, false, w.stalled)
+}
+
+func TestNoUnresponsiveEventForDeadProcess(t *testing.T) {
+	now := time.Now()
+	w := newWatch(newSnapshot(now, 1000, 1000))
+
+	if event := w.update("1", newSnapshot(now.Add(time.Minute), 1000, 1000), 5*time.Second, false); event != nil {
+		t.Errorf("no event is expected for a dead process, but %v", event)
+	}
+}
+
+func TestThresholdDisabled(t *testing.T) {
+	now := time.Now()
+	w := newWatch(newSnapshot(now, 1000, 1000))
+
+	if event := w.update("1", newSnapshot(now.Add(time.Hour), 1000, 1000), 0, true); event != nil {
+		t.Errorf("no event is expected if the threshold is 0, but %v", event)
+	}
+}
+
+func TestUnresponsiveFromFile(t *testing.T) {
+	counters := hsperftest.ReadListing(t, "jdk17-g1.txt")
+	first := hsperftest.Read(t, counters)
+	w := newWatch(first)
+
+	// the file is not updated
+	s := hsperftest.Read(t, counters)
+	s.Time = first.Time.Add(10 * time.Second)
+	event := w.update("1", s, 5*time.Second, true)
+	if event == nil {
+		t.Fatalf("the unresponsive event is expected")
+	}
+	hsperftest.AssertEquals(t, KindUnresponsive, event["kind"])
+	hsperftest.AssertEquals(t, int64(10234567890), event["hrt_ticks"])
+	hsperftest.AssertEquals(t, int64(241), hsperftest.GetValue(t, event, "safepoint.total"))
+	hsperftest.AssertEquals(t, "No GC", hsperftest.GetValue(t, event, "gc.cause"))
+}
@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"strconv"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+// Roles of memory pools.
+const (
+	RoleEden                 = "eden"
+	RoleSurvivor             = "survivor"
+	RoleOld                  = "old"
+	RoleMetaspace            = "metaspace"
+	RoleCompressedClassSpace = "compressed_class_space"
+)
+
+// Types of memory pools.
+const (
+	TypeHeap    = "heap"
+	TypeNonHeap = "non_heap"
+)
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"sun/gc/"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "memory", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships one event per memory pool of each Java process.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler *hsperfdata.Sampler
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns an event
+// for each memory pool. Errors are returned only if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		pidEvents := poolEvents(pid, current.Longs, current.Strings)
+		current.AddLabels(pidEvents...)
+		events = append(events, pidEvents...)
+	})
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch memory metrics for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+// SpaceRole returns the role of the space in the generation. Well-known space
+// names are used if they are available, otherwise the role is guessed from
+// the indices: the first space of the young generation is eden and the
+// others are survivors. The third generation is the PermGen until JDK 7.
+func SpaceRole(gen, space int, name string) string {
+	switch name {
+	case "eden":
+		return RoleEden
+	case "s0", "s1":
+		return RoleSurvivor
+	case "old":
+		return RoleOld
+	case "perm":
+		return RoleMetaspace
+	}
+
+	switch gen {
+	case 0:
+		if space == 0 {
+			return RoleEden
+		}
+		return RoleSurvivor
+	case 1:
+		return RoleOld
+	case 2:
+		return RoleMetaspace
+	}
+	return ""
+}
+
+// usage builds used, capacity, max and initial capacity of a pool.
+// It returns false if the used bytes is not available.
+func usage(longs map[string]int64, prefix, initName string) (common.MapStr, bool) {
+	used, ok := longs[prefix+"used"]
+	if !ok {
+		return nil, false
+	}
+
+	usedMapStr := common.MapStr{"bytes": used}
+	result := common.MapStr{"used": usedMapStr}
+	if v, ok := longs[prefix+"capacity"]; ok {
+		result["capacity"] = common.MapStr{"bytes": v}
+		if v > 0 {
+			usedMapStr["pct"] = float64(used) / float64(v)
+		}
+	}
+	if v, ok := longs[prefix+"maxCapacity"]; ok {
+		result["max"] = common.MapStr{"bytes": v}
+		if v > 0 {
+			usedMapStr["max_pct"] = float64(used) / float64(v)
+		}
+	}
+	if v, ok := longs[prefix+initName]; ok {
+		result["init"] = common.MapStr{"bytes": v}
+	}
+	return result, true
+}
+
+// poolEvents builds events for all spaces in generations, the metaspace and
+// the compressed class space.
+func poolEvents(pid string, longs map[string]int64, strs map[string]string) []common.MapStr {
+	var events []common.MapStr
+
+	for gen := 0; ; gen++ {
+		genPrefix := "sun/gc/generation/" + strconv.Itoa(gen) + "/"
+		if _, ok := longs[genPrefix+"capacity"]; !ok {
+			break
+		}
+
+		for space := 0; ; space++ {
+			prefix := genPrefix + "space/" + strconv.Itoa(space) + "/"
+			event, ok := usage(longs, prefix, "initCapacity")
+			if !ok {
+				break
+			}
+
+			name := strs[prefix+"name"]
+			role := SpaceRole(gen, space, name)
+			poolType := TypeHeap
+			if role == RoleMetaspace {
+				poolType = TypeNonHeap
+			}
+
+			pool := common.MapStr{
+				"name":  name,
+				"type":  poolType,
+				"space": space,
+				"generation": common.MapStr{
+					"index": gen,
+					"name":  strs[genPrefix+"name"],
+				},
+			}
+			if role != "" {
+				pool["role"] = role
+			}
+			event["pid"] = pid
+			event["pool"] = pool
+			events = append(events, event)
+		}
+	}
+
+	for _, area := range []struct{ name, role string }{
+		{"metaspace", RoleMetaspace},
+		{"compressedclassspace", RoleCompressedClassSpace},
+	} {
+		event, ok := usage(longs, "sun/gc/"+area.name+"/", "minCapacity")
+		if !ok {
+			continue
+		}
+		event["pid"] = pid
+		event["pool"] = common.MapStr{
+			"name": area.name,
+			"type": TypeNonHeap,
+			"role": area.role,
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func TestSpaceRole(t *testing.T) {
+	hsperftest.AssertEquals(t, RoleEden, SpaceRole(0, 0, "eden"))
+	hsperftest.AssertEquals(t, RoleSurvivor, SpaceRole(0, 2, "s1"))
+	hsperftest.AssertEquals(t, RoleOld, SpaceRole(1, 0, "space"))
+	hsperftest.AssertEquals(t, RoleMetaspace, SpaceRole(2, 0, "perm"))
+	hsperftest.AssertEquals(t, RoleSurvivor, SpaceRole(0, 1, ""))
+	hsperftest.AssertEquals(t, "", SpaceRole(3, 0, ""))
+}
+
+func TestPoolEvents(t *testing.T) {
+	longs := map[string]int64{
+		"sun/gc/generation/0/capacity":             300,
+		"sun/gc/generation/0/space/0/used":         100,
+		"sun/gc/generation/0/space/0/capacity":     200,
+		"sun/gc/generation/0/space/0/maxCapacity":  400,
+		"sun/gc/generation/0/space/0/initCapacity": 50,
+		"sun/gc/generation/0/space/1/used":         10,
+		"sun/gc/generation/0/space/1/capacity":     50,
+		"sun/gc/generation/1/capacity":             700,
+		"sun/gc/generation/1/space/0/used":         350,
+		"sun/gc/generation/1/space/0/capacity":     700,
+		"sun/gc/metaspace/used":                    40,
+		"sun/gc/metaspace/capacity":                80,
+		"sun/gc/metaspace/minCapacity":             0,
+		"sun/gc/compressedclassspace/used":         4,
+		"sun/gc/compressedclassspace/capacity":     8,
+		"sun/gc/compressedclassspace/maxCapacity":  16,
+		"sun/gc/compressedclassspace/minCapacity":  0,
+	}
+	strs := map[string]string{
+		"sun/gc/generation/0/name":         "new",
+		"sun/gc/generation/0/space/0/name": "eden",
+		"sun/gc/generation/0/space/1/name": "s0",
+		"sun/gc/generation/1/name":         "old",
+		"sun/gc/generation/1/space/0/name": "old",
+	}
+
+	events := poolEvents("1", longs, strs)
+	if len(events) != 5 {
+		t.Fatalf("5 events are expected, but %v", len(events))
+	}
+
+	eden := events[0]
+	hsperftest.AssertEquals(t, "1", eden["pid"])
+	hsperftest.AssertEquals(t, "eden", hsperftest.GetValue(t, eden, "pool.name"))
+	hsperftest.AssertEquals(t, RoleEden, hsperftest.GetValue(t, eden, "pool.role"))
+	hsperftest.AssertEquals(t, TypeHeap, hsperftest.GetValue(t, eden, "pool.type"))
+	hsperftest.AssertEquals(t, "new", hsperftest.GetValue(t, eden, "pool.generation.name"))
+	hsperftest.AssertEquals(t, int64(100), hsperftest.GetValue(t, eden, "used.bytes"))
+	hsperftest.AssertEquals(t, int64(200), hsperftest.GetValue(t, eden, "capacity.bytes"))
+	hsperftest.AssertEquals(t, int64(400), hsperftest.GetValue(t, eden, "max.bytes"))
+	hsperftest.AssertEquals(t, int64(50), hsperftest.GetValue(t, eden, "init.bytes"))
+	hsperftest.AssertEquals(t, 0.5, hsperftest.GetValue(t, eden, "used.pct"))
+	hsperftest.AssertEquals(t, 0.25, hsperftest.GetValue(t, eden, "used.max_pct"))
+
+	hsperftest.AssertEquals(t, RoleSurvivor, hsperftest.GetValue(t, events[1], "pool.role"))
+	hsperftest.AssertEquals(t, RoleOld, hsperftest.GetValue(t, events[2], "pool.role"))
+
+	metaspace := events[3]
+	hsperftest.AssertEquals(t, RoleMetaspace, hsperftest.GetValue(t, metaspace, "pool.role"))
+	hsperftest.AssertEquals(t, TypeNonHeap, hsperftest.GetValue(t, metaspace, "pool.type"))
+	hsperftest.AssertEquals(t, int64(0), hsperftest.GetValue(t, metaspace, "init.bytes"))
+	if _, err := metaspace.GetValue("max"); err == nil {
+		t.Errorf("max should not be shipped if it is not available")
+	}
+
+	hsperftest.AssertEquals(t, RoleCompressedClassSpace, hsperftest.GetValue(t, events[4], "pool.role"))
+}
+
+func TestG1PoolEvents(t *testing.T) {
+	s := hsperftest.Read(t, hsperftest.ReadListing(t, "jdk17-g1.txt"))
+
+	events := poolEvents("1", s.Longs, s.Strings)
+	if len(events) != 6 {
+		t.Fatalf("6 events are expected, but %v", len(events))
+	}
+	hsperftest.AssertEquals(t, RoleEden, hsperftest.GetValue(t, events[0], "pool.role"))
+	hsperftest.AssertEquals(t, int64(83886080), hsperftest.GetValue(t, events[0], "used.bytes"))
+	hsperftest.AssertEquals(t, RoleSurvivor, hsperftest.GetValue(t, events[2], "pool.role"))
+	hsperftest.AssertEquals(t, int64(8388608), hsperftest.GetValue(t, events[2], "used.bytes"))
+	hsperftest.AssertEquals(t, RoleOld, hsperftest.GetValue(t, events[3], "pool.role"))
+	hsperftest.AssertEquals(t, int64(52428800), hsperftest.GetValue(t, events[3], "used.bytes"))
+	hsperftest.AssertEquals(t, RoleMetaspace, hsperftest.GetValue(t, events[4], "pool.role"))
+	hsperftest.AssertEquals(t, RoleCompressedClassSpace, hsperftest.GetValue(t, events[5], "pool.role"))
+}
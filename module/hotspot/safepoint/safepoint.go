@@ -0,0 +1,186 @@
+package safepoint
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+const (
+	safepoints      = "sun/rt/safepoints"
+	safepointTime   = "sun/rt/safepointTime"
+	syncTime        = "sun/rt/safepointSyncTime"
+	applicationTime = "sun/rt/applicationTime"
+)
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"sun/rt/applicationTime", "sun/rt/safepoint"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "safepoint", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships an event of safepoints in each period of each Java process.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler      *hsperfdata.Sampler
+	counterReset string
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+		counterReset:  config.CounterReset,
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns an event
+// for each process. Errors are returned only if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		event := safepointEvent(pid, current, previous, m.Module().Config().Period, m.counterReset)
+		current.AddLabels(event)
+		events = append(events, event)
+	})
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch safepoint metrics for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+// hasReset returns true if any counter goes backwards, i.e. counters have
+// been reset by the restart of the JVM which reuses the PID. Counters which
+// go backwards are recorded in resets, and null is true if values of the
+// period should be null by the counter_reset policy.
+func hasReset(cur, prev map[string]int64, resets *hsperfdata.Resets) (reset, null bool) {
+	for _, name := range []string{safepoints, safepointTime, syncTime, applicationTime} {
+		c, curOk := cur[name]
+		p, prevOk := prev[name]
+		if curOk && prevOk && c < p {
+			reset = true
+			null = resets.Add(name)
+		}
+	}
+	return reset, null
+}
+
+// delta returns the increase of the counter in the period. The current value
+// is the increase if counters have been reset.
+func delta(name string, cur, prev map[string]int64, reset bool) (int64, bool) {
+	c, ok := cur[name]
+	if !ok {
+		return 0, false
+	}
+	if reset {
+		return c, true
+	}
+	p, ok := prev[name]
+	if !ok {
+		return 0, false
+	}
+	return c - p, true
+}
+
+// safepointEvent builds the event of the process. Values of the period are
+// added if the previous snapshot is available. If counters go backwards,
+// values of the period are counted from zero or null by the counter_reset
+// policy.
+func safepointEvent(pid string, cur, prev *hsperfdata.Snapshot, period time.Duration, policy string) common.MapStr {
+	prevLongs, prevTime := hsperfdata.PreviousLongs(prev)
+	elapsed := hsperfdata.Elapsed(cur.Longs, prevLongs, cur.Time, prevTime, period)
+
+	event := common.MapStr{"pid": pid}
+	if elapsed.Seconds > 0 {
+		event["interval"] = elapsed.ToMapStr()
+	}
+
+	ms := func(ticks int64) float64 {
+		v, _ := hsperfdata.TicksToMillis(ticks, cur.Longs)
+		return v
+	}
+	if _, ok := hsperfdata.TicksToMillis(0, cur.Longs); !ok {
+		return event // sun.os.hrt.frequency is not available
+	}
+
+	if v, ok := cur.Longs[safepoints]; ok {
+		event["total"] = v
+	}
+	pause := common.MapStr{}
+	if v, ok := cur.Longs[safepointTime]; ok {
+		pause["total"] = common.MapStr{"ms": ms(v)}
+	}
+	sync := common.MapStr{}
+	if v, ok := cur.Longs[syncTime]; ok {
+		sync["total"] = common.MapStr{"ms": ms(v)}
+	}
+
+	if prev != nil {
+		resets := hsperfdata.NewResets(policy)
+		reset, null := hasReset(cur.Longs, prevLongs, resets)
+		resets.AddTo(event)
+		if null {
+			event["count"] = nil
+			event["application"] = nil
+			pause["ms"] = nil
+			sync["ms"] = nil
+			return withPauseAndSync(event, pause, sync)
+		}
+
+		count, countOk := delta(safepoints, cur.Longs, prevLongs, reset)
+		if countOk {
+			event["count"] = count
+		}
+		pauseTicks, pauseOk := delta(safepointTime, cur.Longs, prevLongs, reset)
+		if pauseOk {
+			pause["ms"] = ms(pauseTicks)
+			if countOk && count > 0 {
+				pause["avg"] = common.MapStr{"ms": ms(pauseTicks) / float64(count)}
+			}
+		}
+		if syncTicks, ok := delta(syncTime, cur.Longs, prevLongs, reset); ok {
+			sync["ms"] = ms(syncTicks)
+			if countOk && count > 0 {
+				sync["avg"] = common.MapStr{"ms": ms(syncTicks) / float64(count)}
+			}
+		}
+		if appTicks, ok := delta(applicationTime, cur.Longs, prevLongs, reset); ok {
+			event["application"] = common.MapStr{"ms": ms(appTicks)}
+			if pauseOk && appTicks+pauseTicks > 0 {
+				pause["pct"] = float64(pauseTicks) / float64(appTicks+pauseTicks)
+			}
+		}
+	}
+
+	return withPauseAndSync(event, pause, sync)
+}
+
+func withPauseAndSync(event, pause, sync common.MapStr) common.MapStr {
+	if len(pause) > 0 {
+		event["pause"] = pause
+	}
+	if len(sync) > 0 {
+		event["sync"] = sync
+	}
+	return event
+}
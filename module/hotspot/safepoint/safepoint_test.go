@@ -0,0 +1,90 @@
+package safepoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func newSnapshot(now time.Time, ticks, count, pause, sync, app int64) *hsperfdata.Snapshot {
+	return hsperftest.NewSnapshot(now, ticks, hsperftest.Values{
+		"sun/rt/safepoints":        count,
+		"sun/rt/safepointTime":     pause,
+		"sun/rt/safepointSyncTime": sync,
+		"sun/rt/applicationTime":   app,
+	})
+}
+
+func TestFirstSafepointEvent(t *testing.T) {
+	event := safepointEvent("1", newSnapshot(time.Now(), 1000, 10, 50, 5, 900), nil, time.Second, hsperfdata.ResetValue)
+
+	hsperftest.AssertEquals(t, int64(10), event["total"])
+	hsperftest.AssertEquals(t, 50.0, hsperftest.GetValue(t, event, "pause.total.ms"))
+	if _, exists := event["count"]; exists {
+		t.Errorf("count should not be shipped at the first fetch")
+	}
+}
+
+func TestSafepointEventWithPrevious(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 10, 50, 5, 900)
+	cur := newSnapshot(now.Add(time.Second), 2000, 14, 150, 13, 1800)
+
+	event := safepointEvent("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, int64(4), event["count"])
+	hsperftest.AssertEquals(t, 100.0, hsperftest.GetValue(t, event, "pause.ms"))
+	hsperftest.AssertEquals(t, 25.0, hsperftest.GetValue(t, event, "pause.avg.ms"))
+	hsperftest.AssertEquals(t, 0.1, hsperftest.GetValue(t, event, "pause.pct"))
+	hsperftest.AssertEquals(t, 8.0, hsperftest.GetValue(t, event, "sync.ms"))
+	hsperftest.AssertEquals(t, 2.0, hsperftest.GetValue(t, event, "sync.avg.ms"))
+	hsperftest.AssertEquals(t, 900.0, hsperftest.GetValue(t, event, "application.ms"))
+}
+
+func TestSafepointEventAfterReset(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 100000, 1000, 5000, 500, 90000)
+	cur := newSnapshot(now.Add(time.Second), 1000, 2, 30, 4, 900)
+
+	event := safepointEvent("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, true, event["reset"])
+	hsperftest.AssertEquals(t, int64(2), event["count"])
+	hsperftest.AssertEquals(t, 30.0, hsperftest.GetValue(t, event, "pause.ms"))
+	hsperftest.AssertEquals(t, 2.0, hsperftest.GetValue(t, event, "sync.avg.ms"))
+}
+
+func TestSafepointEventAfterResetNull(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 100000, 1000, 5000, 500, 90000)
+	cur := newSnapshot(now.Add(time.Second), 1000, 2, 30, 4, 900)
+
+	event := safepointEvent("1", cur, prev, time.Second, hsperfdata.ResetNull)
+	hsperftest.AssertEquals(t, true, event["reset"])
+	hsperftest.AssertEquals(t, 4, len(event["reset_counters"].([]string)))
+	if count, exists := event["count"]; !exists || count != nil {
+		t.Errorf("count should be null: %v", count)
+	}
+	hsperftest.AssertEquals(t, nil, hsperftest.GetValue(t, event, "pause.ms"))
+	hsperftest.AssertEquals(t, 30.0, hsperftest.GetValue(t, event, "pause.total.ms"))
+}
+
+func TestSafepointEventFromFile(t *testing.T) {
+	counters := hsperftest.ReadListing(t, "jdk17-g1.txt")
+	prev := hsperftest.Read(t, counters)
+	counters = hsperftest.Set(counters, "sun.os.hrt.ticks", int64(11234567890))
+	counters = hsperftest.Set(counters, "sun.rt.safepoints", int64(245))
+	counters = hsperftest.Set(counters, "sun.rt.safepointTime", int64(28567890))
+	counters = hsperftest.Set(counters, "sun.rt.safepointSyncTime", int64(3523456))
+	counters = hsperftest.Set(counters, "sun.rt.applicationTime", int64(11097234567))
+	cur := hsperftest.Read(t, counters)
+
+	event := safepointEvent("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, int64(245), event["total"])
+	hsperftest.AssertEquals(t, int64(4), event["count"])
+	hsperftest.AssertEquals(t, 4.0, hsperftest.GetValue(t, event, "pause.ms"))
+	hsperftest.AssertEquals(t, 1.0, hsperftest.GetValue(t, event, "pause.avg.ms"))
+	hsperftest.AssertEquals(t, 0.4, hsperftest.GetValue(t, event, "sync.ms"))
+	hsperftest.AssertEquals(t, 0.1, hsperftest.GetValue(t, event, "sync.avg.ms"))
+	hsperftest.AssertEquals(t, 996.0, hsperftest.GetValue(t, event, "application.ms"))
+}
@@ -0,0 +1,171 @@
+package threads
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+)
+
+const (
+	threadsLive    = "java/threads/live"
+	threadsDaemon  = "java/threads/daemon"
+	threadsPeak    = "java/threads/livePeak"
+	threadsStarted = "java/threads/started"
+
+	syncPrefix            = "sun/rt/_sync_"
+	contendedLockAttempts = "ContendedLockAttempts"
+	slowEnter             = "SlowEnter"
+)
+
+// syncCounters maps cumulative sun.rt._sync_* counters to field names.
+// Their diffs and rates are shipped.
+var syncCounters = map[string]string{
+	contendedLockAttempts: "contended_lock_attempts",
+	"FutileWakeups":       "futile_wakeups",
+	"Parks":               "parks",
+	"Notifications":       "notifications",
+	"EmptyNotifications":  "empty_notifications",
+	"Inflations":          "inflations",
+	"Deflations":          "deflations",
+	slowEnter:             "slow_enter",
+	"SlowExit":            "slow_exit",
+	"SlowNotify":          "slow_notify",
+	"SlowNotifyAll":       "slow_notify_all",
+	"FailedSpins":         "failed_spins",
+	"SuccessfulSpins":     "successful_spins",
+	"MonInCirculation":    "monitors_in_circulation",
+	"MonScavenged":        "monitors_scavenged",
+}
+
+// monitorGauges maps sun.rt._sync_* gauges of monitors to field names.
+var monitorGauges = map[string]string{
+	"MonExtant": "extant",
+}
+
+// prefixes are counters which are read for the metricset. Constants and
+// sun.os.hrt counters are always read.
+var prefixes = []string{"java/threads/", "sun/rt/_sync_"}
+
+// init registers the MetricSet with the central registry.
+func init() {
+	if err := mb.Registry.AddMetricSet("hotspot", "threads", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet ships an event of threads and monitor contention of each Java
+// process.
+type MetricSet struct {
+	mb.BaseMetricSet
+	sampler      *hsperfdata.Sampler
+	counterReset string
+}
+
+// New creates a new instance of the MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := hsperfdata.DefaultConfig()
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		sampler:       hsperfdata.NewSampler(base, &config, prefixes),
+		counterReset:  config.CounterReset,
+	}, nil
+}
+
+// Fetch reads counters of all attached Java processes and returns an event
+// for each process. Errors are returned only if no events were collected.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var events []common.MapStr
+	errors := m.sampler.Sample(func(pid string, current, previous *hsperfdata.Snapshot) {
+		event := threadsEvent(pid, current, previous, m.Module().Config().Period, m.counterReset)
+		current.AddLabels(event)
+		events = append(events, event)
+	})
+
+	if errors.HasErrors() {
+		logp.Debug(hsperfdata.DEBUG_SELECTOR, "Could not fetch thread metrics for all processes. Error(s) found: %v", errors.String())
+		if len(events) == 0 {
+			return nil, errors
+		}
+	}
+
+	return events, nil
+}
+
+// threadsEvent builds the event of the process. Diffs, rates and the
+// contention ratio are added if the previous snapshot is available. Counters
+// which go backwards are shipped by the counter_reset policy.
+func threadsEvent(pid string, cur, prev *hsperfdata.Snapshot, period time.Duration, policy string) common.MapStr {
+	prevLongs, prevTime := hsperfdata.PreviousLongs(prev)
+	elapsed := hsperfdata.Elapsed(cur.Longs, prevLongs, cur.Time, prevTime, period)
+
+	event := common.MapStr{"pid": pid}
+	if elapsed.Seconds > 0 {
+		event["interval"] = elapsed.ToMapStr()
+	}
+	resets := hsperfdata.NewResets(policy)
+
+	for name, field := range map[string]string{
+		threadsLive:   "live",
+		threadsDaemon: "daemon",
+		threadsPeak:   "peak",
+	} {
+		if v, ok := cur.Longs[name]; ok {
+			event[field] = v
+		}
+	}
+	if started, ok := hsperfdata.CounterMapStr(threadsStarted, cur.Longs, prevLongs, elapsed, resets); ok {
+		event["started"] = started
+	}
+
+	sync := common.MapStr{}
+	for name, field := range syncCounters {
+		if counter, ok := hsperfdata.CounterMapStr(syncPrefix+name, cur.Longs, prevLongs, elapsed, resets); ok {
+			sync[field] = counter
+		}
+	}
+	monitors := common.MapStr{}
+	for name, field := range monitorGauges {
+		if v, ok := cur.Longs[syncPrefix+name]; ok {
+			monitors[field] = v
+		}
+	}
+	if len(monitors) > 0 {
+		sync["monitors"] = monitors
+	}
+	if ratio, ok := contentionRatio(cur.Longs, prevLongs); ok {
+		sync["contention"] = common.MapStr{"ratio": ratio}
+	}
+	if len(sync) > 0 {
+		event["sync"] = sync
+	}
+	resets.AddTo(event)
+
+	return event
+}
+
+// contentionRatio returns the ratio of contended lock attempts to monitor
+// enters in the slow path since the previous fetch. It is not available if
+// no monitor is entered in the slow path or counters go backwards.
+func contentionRatio(cur, prev map[string]int64) (float64, bool) {
+	var deltas [2]int64
+	for i, name := range []string{contendedLockAttempts, slowEnter} {
+		c, curOk := cur[syncPrefix+name]
+		p, prevOk := prev[syncPrefix+name]
+		if !curOk || !prevOk || c < p {
+			return 0, false
+		}
+		deltas[i] = c - p
+	}
+	if deltas[1] <= 0 {
+		return 0, false
+	}
+	return float64(deltas[0]) / float64(deltas[1]), true
+}
@@ -0,0 +1,75 @@
+package threads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata"
+	"github.com/YaSuenag/hsbeat/module/hotspot/hsperfdata/hsperftest"
+)
+
+func newSnapshot(now time.Time, ticks, started, contended, slowEnter int64) *hsperfdata.Snapshot {
+	return hsperftest.NewSnapshot(now, ticks, hsperftest.Values{
+		"java/threads/live":                  20,
+		"java/threads/daemon":                15,
+		"java/threads/livePeak":              25,
+		"java/threads/started":               started,
+		"sun/rt/_sync_ContendedLockAttempts": contended,
+		"sun/rt/_sync_SlowEnter":             slowEnter,
+		"sun/rt/_sync_MonExtant":             64,
+		"sun/rt/_sync_MonScavenged":          slowEnter * 2,
+	})
+}
+
+func TestFirstThreadsEvent(t *testing.T) {
+	event := threadsEvent("1", newSnapshot(time.Now(), 1000, 30, 5, 10), nil, time.Second, hsperfdata.ResetValue)
+
+	hsperftest.AssertEquals(t, "1", event["pid"])
+	hsperftest.AssertEquals(t, int64(20), event["live"])
+	hsperftest.AssertEquals(t, int64(15), event["daemon"])
+	hsperftest.AssertEquals(t, int64(25), event["peak"])
+	hsperftest.AssertEquals(t, int64(30), hsperftest.GetValue(t, event, "started.total"))
+	hsperftest.AssertEquals(t, int64(5), hsperftest.GetValue(t, event, "sync.contended_lock_attempts.total"))
+	hsperftest.AssertEquals(t, int64(64), hsperftest.GetValue(t, event, "sync.monitors.extant"))
+
+	for _, key := range []string{"started.rate", "sync.contention", "interval"} {
+		if _, err := event.GetValue(key); err == nil {
+			t.Errorf("%v should not be shipped at the first fetch", key)
+		}
+	}
+}
+
+func TestThreadsEventWithPrevious(t *testing.T) {
+	now := time.Now()
+	prev := newSnapshot(now, 1000, 30, 5, 10)
+	cur := newSnapshot(now.Add(2*time.Second), 3000, 34, 15, 50)
+
+	event := threadsEvent("1", cur, prev, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, int64(4), hsperftest.GetValue(t, event, "started.diff"))
+	hsperftest.AssertEquals(t, 2.0, hsperftest.GetValue(t, event, "started.rate"))
+	hsperftest.AssertEquals(t, 5.0, hsperftest.GetValue(t, event, "sync.contended_lock_attempts.rate"))
+	hsperftest.AssertEquals(t, 0.25, hsperftest.GetValue(t, event, "sync.contention.ratio"))
+	hsperftest.AssertEquals(t, int64(80), hsperftest.GetValue(t, event, "sync.monitors_scavenged.diff"))
+	hsperftest.AssertEquals(t, int64(64), hsperftest.GetValue(t, event, "sync.monitors.extant"))
+}
+
+func TestContentionRatioWithoutSlowEnter(t *testing.T) {
+	prev := map[string]int64{"sun/rt/_sync_ContendedLockAttempts": 1, "sun/rt/_sync_SlowEnter": 10}
+	cur := map[string]int64{"sun/rt/_sync_ContendedLockAttempts": 1, "sun/rt/_sync_SlowEnter": 10}
+
+	if _, ok := contentionRatio(cur, prev); ok {
+		t.Errorf("contention ratio should not be available without slow enters")
+	}
+}
+
+func TestThreadsEventFromFile(t *testing.T) {
+	s := hsperftest.Read(t, hsperftest.ReadListing(t, "jdk17-g1.txt"))
+
+	event := threadsEvent("1", s, nil, time.Second, hsperfdata.ResetValue)
+	hsperftest.AssertEquals(t, int64(19), event["live"])
+	hsperftest.AssertEquals(t, int64(12), event["daemon"])
+	hsperftest.AssertEquals(t, int64(21), event["peak"])
+	hsperftest.AssertEquals(t, int64(25), hsperftest.GetValue(t, event, "started.total"))
+	hsperftest.AssertEquals(t, int64(81), hsperftest.GetValue(t, event, "sync.contended_lock_attempts.total"))
+	hsperftest.AssertEquals(t, int64(128), hsperftest.GetValue(t, event, "sync.monitors.extant"))
+}